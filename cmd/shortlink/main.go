@@ -0,0 +1,194 @@
+// Command shortlink runs the short link service.
+//
+// Usage:
+//
+//	shortlink                     serve the API
+//	shortlink migrate up          apply pending migrations
+//	shortlink migrate down [n]    revert the last n migrations, or all
+//	shortlink migrate version     print the current schema version
+//	shortlink --selftest          check the database end to end and exit
+//
+// Configuration is read from the environment; see package config.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/config"
+	"github.com/maojcn/shortlink/internal/events"
+	"github.com/maojcn/shortlink/internal/logging"
+	"github.com/maojcn/shortlink/internal/migrate"
+	"github.com/maojcn/shortlink/internal/outbound"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reservedcodes"
+	"github.com/maojcn/shortlink/internal/selftest"
+	"github.com/maojcn/shortlink/internal/server"
+	"github.com/maojcn/shortlink/internal/tracing"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "shortlink:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 && args[0] == "migrate" {
+		return runMigrate(cfg.Database.DSN, args[1:])
+	}
+	if len(args) > 0 && args[0] != "--selftest" {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	logger, err := logging.New(cfg.Log)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+	if len(args) > 0 {
+		return runSelftest(cfg, logger)
+	}
+	return serve(cfg, logger)
+}
+
+// selftestTimeout bounds the self-test steps; cleanups get their own
+// deadline.
+const selftestTimeout = time.Minute
+
+// runSelftest runs the self-test, prints its report and fails if any step
+// did.
+func runSelftest(cfg *config.Config, logger *zap.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), selftestTimeout)
+	defer cancel()
+	report := selftest.Run(ctx, selftest.Postgres(cfg, logger))
+	if err := report.Write(os.Stdout); err != nil {
+		return err
+	}
+	if !report.OK() {
+		return errors.New("self-test failed")
+	}
+	return nil
+}
+
+// serve runs the API until SIGINT or SIGTERM, then shuts down in the order
+// shutdownSequence gives.
+func serve(cfg *config.Config, logger *zap.Logger) error {
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	seq, err := repository.NewSequenceSource(cfg.Links)
+	if err != nil {
+		return err
+	}
+	repo, err := repository.NewPostgresRepo(cfg.Database, seq, logger)
+	if err != nil {
+		return err
+	}
+	// Covers the early returns below; closing again in the shutdown
+	// sequence is harmless.
+	defer repo.Close()
+
+	if cfg.Database.AutoMigrate {
+		if err := migrateUp(cfg.Database.DSN); err != nil {
+			return err
+		}
+		logger.Info("database schema is up to date")
+	}
+
+	publisher, err := events.NewPublisher(cfg.Events, outbound.NewTrustedClient(cfg.Outbound))
+	if err != nil {
+		return err
+	}
+	reserved, err := reservedcodes.Load(cfg.Links.ReservedCodesFile)
+	if err != nil {
+		return err
+	}
+	srv := server.New(cfg, repo, publisher, reserved, logger)
+	httpServer := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go reserved.ReloadOn(ctx, hup, logger)
+	errc := make(chan error, 1)
+	go func() {
+		logger.Info("listening", zap.String("addr", cfg.Server.Addr))
+		errc <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		logger.Info("shutting down")
+	}
+	closeDB := func(context.Context) error { return repo.Close() }
+	return errors.Join(err, shutdown(shutdownSequence(httpServer.Shutdown, srv.Shutdown, shutdownTracing, closeDB), logger))
+}
+
+func migrateUp(dsn string) error {
+	m, err := migrate.New(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return m.Up()
+}
+
+func runMigrate(dsn string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: shortlink migrate up|down [n]|version")
+	}
+	m, err := migrate.New(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		return m.Up()
+	case "down":
+		n := 0
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n <= 0 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		return m.Down(n)
+	case "version":
+		version, dirty, err := m.Version()
+		if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", version)
+		} else {
+			fmt.Println(version)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Per-step shutdown timeouts. In-flight requests get the longest; the
+// remaining steps only release resources.
+const (
+	httpShutdownTimeout   = 15 * time.Second
+	workerShutdownTimeout = 5 * time.Second
+	flushShutdownTimeout  = 5 * time.Second
+	closeShutdownTimeout  = 5 * time.Second
+)
+
+// shutdownStep is one stage of an ordered shutdown.
+type shutdownStep struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// shutdownSequence returns the order serve shuts down in: the HTTP server
+// stops accepting requests and drains the in-flight ones, the background
+// workers stop, buffered traces are flushed and finally the database is
+// closed, so nothing still running can use a resource that is already
+// gone. A Redis client or write-behind buffer, should the service gain
+// one, belongs after the workers and before Postgres.
+func shutdownSequence(httpServer, workers, traces, db func(context.Context) error) []shutdownStep {
+	return []shutdownStep{
+		{"http server", httpShutdownTimeout, httpServer},
+		{"background workers", workerShutdownTimeout, workers},
+		{"traces", flushShutdownTimeout, traces},
+		{"postgres", closeShutdownTimeout, db},
+	}
+}
+
+// shutdown runs steps in order, each bounded by its own timeout even if
+// stop ignores its context, and returns the errors of the steps that
+// failed. A failed step does not prevent the later ones: the process is
+// exiting and their resources still need releasing.
+func shutdown(steps []shutdownStep, logger *zap.Logger) error {
+	var errs []error
+	for _, step := range steps {
+		start := time.Now()
+		err := runStep(step)
+		if err != nil {
+			logger.Warn("shutdown step failed", zap.String("step", step.name), zap.Duration("elapsed", time.Since(start)), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+			continue
+		}
+		logger.Info("shutdown step done", zap.String("step", step.name), zap.Duration("elapsed", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
+
+func runStep(step shutdownStep) error {
+	ctx, cancel := context.WithTimeout(context.Background(), step.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- step.stop(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// closeRecorder stands in for the resources serve shuts down and records
+// the order they are stopped in.
+type closeRecorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *closeRecorder) stop(name string, err error) func(context.Context) error {
+	return func(context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.order = append(r.order, name)
+		return err
+	}
+}
+
+func (r *closeRecorder) step(name string, timeout time.Duration, err error) shutdownStep {
+	return shutdownStep{name: name, timeout: timeout, stop: r.stop(name, err)}
+}
+
+func TestShutdownSequenceOrder(t *testing.T) {
+	var rec closeRecorder
+	errWorkers := errors.New("worker stuck")
+	err := shutdown(shutdownSequence(
+		rec.stop("http", nil),
+		rec.stop("workers", errWorkers),
+		rec.stop("traces", nil),
+		rec.stop("postgres", nil),
+	), zap.NewNop())
+
+	want := []string{"http", "workers", "traces", "postgres"}
+	if len(rec.order) != len(want) {
+		t.Fatalf("order = %v, want %v", rec.order, want)
+	}
+	for i := range want {
+		if rec.order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", rec.order, want)
+		}
+	}
+	if !errors.Is(err, errWorkers) {
+		t.Errorf("error = %v, want it to include %v", err, errWorkers)
+	}
+}
+
+func TestShutdownBoundsEachStep(t *testing.T) {
+	var rec closeRecorder
+	block := make(chan struct{})
+	defer close(block)
+	hung := shutdownStep{name: "hung", timeout: 20 * time.Millisecond, stop: func(context.Context) error {
+		<-block // ignores its context
+		return nil
+	}}
+
+	start := time.Now()
+	err := shutdown([]shutdownStep{hung, rec.step("postgres", time.Second, nil)}, zap.NewNop())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown took %v", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(rec.order) != 1 || rec.order[0] != "postgres" {
+		t.Errorf("later steps = %v, want postgres still closed", rec.order)
+	}
+}
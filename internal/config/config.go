@@ -0,0 +1,222 @@
+// Package config loads the service configuration from the environment.
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config is the full service configuration. Each field of a section is
+// read from SECTION_FIELD, e.g. DATABASE_DSN or
+// DATABASE_CONNECT_MAX_ATTEMPTS.
+type Config struct {
+	Database  DatabaseConfig
+	Events    EventsConfig
+	Links     LinkConfig
+	LinkCheck LinkCheckConfig `split_words:"true"`
+	Log       LogConfig
+	Outbound  OutboundConfig
+	Server    ServerConfig
+	Tracing   TracingConfig
+	Worker    WorkerConfig
+}
+
+// DatabaseConfig configures the Postgres connection. NewPostgresRepo makes
+// up to ConnectMaxAttempts connection attempts, waiting ConnectBaseDelay
+// after the first failure and doubling the wait after each one.
+// AutoMigrate applies pending migrations on startup; disable it where
+// migrations are run separately with "shortlink migrate up".
+type DatabaseConfig struct {
+	DSN                string        `split_words:"true" required:"true"`
+	ConnectMaxAttempts int           `split_words:"true" default:"5"`
+	ConnectBaseDelay   time.Duration `split_words:"true" default:"500ms"`
+	AutoMigrate        bool          `split_words:"true" default:"true"`
+}
+
+// EventsConfig selects where link lifecycle events are published. Sink is
+// "none" or "webhook"; Source is the CloudEvents source attribute.
+type EventsConfig struct {
+	Sink       string `default:"none"`
+	WebhookURL string `split_words:"true"`
+	Source     string `default:"/shortlink"`
+}
+
+// LinkConfig controls how short links are created. CodeSource is "serial",
+// where codes follow the row ID, or "obfuscated", where the ID is permuted
+// with CodeKey first so codes do not reveal how many links exist.
+// RejectConfusableHosts refuses destinations whose host looks like a
+// homograph of another domain instead of only warning about them.
+// ReservedCodesFile lists codes, one per line, that can never be chosen as
+// custom codes; it is re-read on SIGHUP. A destination that clearly lacks
+// a scheme, such as "example.com/a", gets DefaultScheme ("http" or
+// "https") prepended unless StrictScheme is set, in which case it is
+// rejected. MaxBatchItems caps the links in one request to any batch
+// endpoint, and BatchWorkers is how many goroutines validate a batch's
+// links in parallel; the inserts always run one after another in the
+// batch's transaction. QRCacheMaxAge is the max-age of the Cache-Control
+// header on QR code images; 0 makes clients revalidate every time.
+type LinkConfig struct {
+	CodeSource            string        `split_words:"true" default:"serial"`
+	CodeKey               string        `split_words:"true"`
+	RejectConfusableHosts bool          `split_words:"true"`
+	ReservedCodesFile     string        `split_words:"true"`
+	DefaultScheme         string        `split_words:"true" default:"https"`
+	StrictScheme          bool          `split_words:"true"`
+	MaxBatchItems         int           `split_words:"true" default:"1000"`
+	BatchWorkers          int           `split_words:"true" default:"4"`
+	QRCacheMaxAge         time.Duration `split_words:"true" default:"24h"`
+}
+
+// LinkCheckConfig bounds the destination liveness check: at most MaxLinks
+// links per call, Concurrency requests in flight, Timeout per request, and
+// results reused for CacheTTL.
+type LinkCheckConfig struct {
+	MaxLinks    int           `split_words:"true" default:"100"`
+	Concurrency int           `default:"8"`
+	Timeout     time.Duration `default:"5s"`
+	CacheTTL    time.Duration `split_words:"true" default:"1m"`
+}
+
+// LogConfig configures the logger. Format is "json", "console" or
+// "logfmt"; Level is any zap level name.
+type LogConfig struct {
+	Level  string `default:"info"`
+	Format string `default:"json"`
+}
+
+// OutboundConfig configures the shared client for requests the service
+// makes to user-supplied URLs. Private, loopback and link-local addresses
+// are refused unless AllowPrivateNetworks is set. ProxyURL, when set, is
+// the http or https proxy requests go through, except for hosts matching
+// an entry of NoProxy: a host name, a ".domain" suffix, an IP or a CIDR,
+// as in the NO_PROXY environment variable.
+type OutboundConfig struct {
+	Timeout              time.Duration `default:"10s"`
+	AllowPrivateNetworks bool          `split_words:"true"`
+	ProxyURL             string        `split_words:"true"`
+	NoProxy              []string      `split_words:"true"`
+}
+
+// ServerConfig configures the HTTP server. Addr is the listen address and
+// BaseURL the public URL short codes are appended to, such as
+// "https://sho.rt"; when empty it is derived from each request's host.
+// RequestTimeout is the deadline each request's context gets; 0 disables
+// it. AllowPrettyJSON lets clients request indented JSON with
+// ?pretty=true; it is off by default to keep responses small. JSONCharset
+// is the charset parameter sent on every JSON Content-Type. AllowedHosts,
+// when non-empty, is the comma-separated list of Host header values the
+// server answers; any other host gets 400. TrustedProxies lists the CIDRs
+// or IPs of the proxies whose X-Forwarded-For is believed when working out
+// a client's IP; by default none is. MaxConnsPerIP caps the requests one
+// client IP may have in flight, answering the excess with 429; 0, the
+// default, disables it. ConnLimitExempt lists the CIDRs or IPs it never
+// applies to, loopback by default. ReadHeaderTimeout bounds how long a
+// connection may take to send its request headers, so that connections
+// which never finish a request do not pile up uncounted. EmitServerTiming
+// adds a Server-Timing header with each request's database and total time;
+// it exposes backend timings, so leave it off in production.
+type ServerConfig struct {
+	Addr              string        `default:":8080"`
+	BaseURL           string        `split_words:"true"`
+	RequestTimeout    time.Duration `split_words:"true" default:"10s"`
+	AllowPrettyJSON   bool          `split_words:"true"`
+	JSONCharset       string        `split_words:"true" default:"utf-8"`
+	AllowedHosts      []string      `split_words:"true"`
+	TrustedProxies    []string      `split_words:"true"`
+	MaxConnsPerIP     int           `split_words:"true"`
+	ConnLimitExempt   []string      `split_words:"true" default:"127.0.0.0/8,::1"`
+	ReadHeaderTimeout time.Duration `split_words:"true" default:"5s"`
+	EmitServerTiming  bool          `split_words:"true"`
+}
+
+// TracingConfig configures OpenTelemetry tracing. When Enabled, spans are
+// exported over OTLP/HTTP to Endpoint, a collector URL such as
+// "http://localhost:4318", and SampleRatio of new traces are kept.
+type TracingConfig struct {
+	Enabled     bool    `default:"false"`
+	Endpoint    string  `default:"http://localhost:4318"`
+	SampleRatio float64 `split_words:"true" default:"1"`
+	ServiceName string  `split_words:"true" default:"shortlink"`
+}
+
+// WorkerConfig configures the background jobs. SweepInterval is how often
+// expired links are deleted.
+type WorkerConfig struct {
+	SweepInterval time.Duration `split_words:"true" default:"1h"`
+}
+
+// Load reads the configuration from the environment.
+func Load() (*Config, error) {
+	var cfg Config
+	if err := envconfig.Process("", &cfg); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Server.validate(); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Links.validate(); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Outbound.validate(); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (c OutboundConfig) validate() error {
+	if c.ProxyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return fmt.Errorf("OUTBOUND_PROXY_URL must be an absolute http or https URL without path or query")
+	}
+	return nil
+}
+
+func (c LinkConfig) validate() error {
+	if c.DefaultScheme != "http" && c.DefaultScheme != "https" {
+		return fmt.Errorf("LINKS_DEFAULT_SCHEME %q must be http or https", c.DefaultScheme)
+	}
+	if c.QRCacheMaxAge < 0 {
+		return fmt.Errorf("LINKS_QR_CACHE_MAX_AGE %s must not be negative", c.QRCacheMaxAge)
+	}
+	return nil
+}
+
+func (c ServerConfig) validate() error {
+	if err := validateNetworks("SERVER_TRUSTED_PROXIES", c.TrustedProxies); err != nil {
+		return err
+	}
+	if err := validateNetworks("SERVER_CONN_LIMIT_EXEMPT", c.ConnLimitExempt); err != nil {
+		return err
+	}
+	if c.BaseURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("SERVER_BASE_URL %q must be an absolute http or https URL without query or fragment", c.BaseURL)
+	}
+	return nil
+}
+
+// validateNetworks checks that every entry of the list read from key is a
+// CIDR or an IP address.
+func validateNetworks(key string, list []string) error {
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if _, err := netip.ParsePrefix(s); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(s); err != nil {
+			return fmt.Errorf("%s entry %q must be a CIDR or an IP address", key, s)
+		}
+	}
+	return nil
+}
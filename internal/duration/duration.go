@@ -0,0 +1,72 @@
+// Package duration parses the expiry durations accepted by the link API.
+//
+// Accepted formats:
+//
+//   - Go duration strings, as understood by time.ParseDuration: "720h",
+//     "90m", "1h30m".
+//   - A whole number followed by a calendar shorthand unit: "30d" (days),
+//     "2w" (weeks) or "3mo" (months of exactly 30 days). Units cannot be
+//     combined, so "1w2d" is rejected; write "9d" instead.
+//
+// "m" keeps its Go meaning of minutes; months are always "mo". Zero and
+// negative durations are rejected because an expiry must lie in the future.
+package duration
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// ErrNotPositive is returned when the parsed duration is zero or negative.
+var ErrNotPositive = errors.New("duration: must be positive")
+
+// Ordered so that "mo" is matched before any single-letter suffix.
+var units = []struct {
+	suffix string
+	size   time.Duration
+}{
+	{"mo", 30 * day},
+	{"w", 7 * day},
+	{"d", day},
+}
+
+// Parse converts s into a positive time.Duration.
+func Parse(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("duration: empty value")
+	}
+
+	for _, u := range units {
+		num, ok := strings.CutSuffix(s, u.suffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil || strings.HasPrefix(num, "+") {
+			return 0, fmt.Errorf("duration: invalid value %q", s)
+		}
+		if n <= 0 {
+			return 0, ErrNotPositive
+		}
+		if n > math.MaxInt64/int64(u.size) {
+			return 0, fmt.Errorf("duration: %q is out of range", s)
+		}
+		return time.Duration(n) * u.size, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("duration: invalid value %q", s)
+	}
+	if d <= 0 {
+		return 0, ErrNotPositive
+	}
+	return d, nil
+}
@@ -0,0 +1,60 @@
+package duration
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"30d", 30 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1mo", 30 * 24 * time.Hour},
+		{"720h", 720 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"1h30m", 90 * time.Minute},
+		{" 7d ", 7 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"d",
+		"abc",
+		"30",
+		"1.5d",
+		"1w2d",
+		"+3d",
+		"30 days",
+		"30y",
+		"9999999999999w",
+	}
+	for _, in := range tests {
+		if d, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %v, want error", in, d)
+		}
+	}
+}
+
+func TestParseNotPositive(t *testing.T) {
+	for _, in := range []string{"-30d", "0d", "-2w", "-720h", "0s"} {
+		if _, err := Parse(in); !errors.Is(err, ErrNotPositive) {
+			t.Errorf("Parse(%q) error = %v, want ErrNotPositive", in, err)
+		}
+	}
+}
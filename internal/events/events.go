@@ -0,0 +1,86 @@
+// Package events publishes link lifecycle events as CloudEvents 1.0.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+// Event types emitted for links.
+const (
+	TypeLinkCreated        = "com.shortlink.link.created"
+	TypeLinkUpdated        = "com.shortlink.link.updated"
+	TypeLinkDeleted        = "com.shortlink.link.deleted"
+	TypeLinkClickMilestone = "com.shortlink.link.click_milestone"
+)
+
+// SpecVersion is the CloudEvents specification version of every Event.
+const SpecVersion = "1.0"
+
+// Event is a CloudEvent in structured JSON form.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	Type            string    `json:"type"`
+	Source          string    `json:"source"`
+	ID              string    `json:"id"`
+	Time            time.Time `json:"time"`
+	Subject         string    `json:"subject,omitempty"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	Data            any       `json:"data,omitempty"`
+}
+
+// New returns an Event of type typ from source about subject, with a
+// random ID and the current time.
+func New(typ, source, subject string, data any) Event {
+	return Event{
+		SpecVersion:     SpecVersion,
+		Type:            typ,
+		Source:          source,
+		ID:              newID(),
+		Time:            time.Now().UTC(),
+		Subject:         subject,
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// newID returns 16 random bytes, hex encoded.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Publisher delivers events to a sink.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// NopPublisher discards every event.
+type NopPublisher struct{}
+
+// Publish implements Publisher.
+func (NopPublisher) Publish(context.Context, Event) error { return nil }
+
+// NewPublisher returns the Publisher selected by cfg.Sink: "none" or
+// "webhook". Webhook deliveries use client.
+func NewPublisher(cfg config.EventsConfig, client *http.Client) (Publisher, error) {
+	switch cfg.Sink {
+	case "none":
+		return NopPublisher{}, nil
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return nil, errors.New("events: webhook sink requires EVENTS_WEBHOOK_URL")
+		}
+		return NewWebhookPublisher(cfg.WebhookURL, client), nil
+	default:
+		return nil, fmt.Errorf("events: unknown sink %q", cfg.Sink)
+	}
+}
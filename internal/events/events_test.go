@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	before := time.Now()
+	e := New(TypeLinkCreated, "/shortlink", "abc", map[string]string{"code": "abc"})
+
+	if e.SpecVersion != "1.0" || e.Type != TypeLinkCreated || e.Source != "/shortlink" || e.Subject != "abc" {
+		t.Errorf("event = %+v", e)
+	}
+	if len(e.ID) != 32 {
+		t.Errorf("ID = %q, want 32 hex characters", e.ID)
+	}
+	if e.Time.Before(before.Add(-time.Second)) || e.Time.Location() != time.UTC {
+		t.Errorf("Time = %v, want now in UTC", e.Time)
+	}
+	if other := New(TypeLinkCreated, "/shortlink", "abc", nil); other.ID == e.ID {
+		t.Error("two events share an ID")
+	}
+}
+
+func TestWebhookPublisherSendsStructuredEvent(t *testing.T) {
+	var gotType string
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	e := New(TypeLinkCreated, "/shortlink", "10", map[string]string{"code": "10"})
+	if err := NewWebhookPublisher(srv.URL, srv.Client()).Publish(context.Background(), e); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	if gotType != ContentType {
+		t.Errorf("Content-Type = %q, want %q", gotType, ContentType)
+	}
+	for _, attr := range []string{"specversion", "type", "source", "id", "time"} {
+		if _, ok := got[attr]; !ok {
+			t.Errorf("event is missing %q: %v", attr, got)
+		}
+	}
+	if got["type"] != TypeLinkCreated || got["id"] != e.ID || got["time"] != e.Time.Format(time.RFC3339Nano) {
+		t.Errorf("event = %v", got)
+	}
+	if data, _ := got["data"].(map[string]any); data["code"] != "10" {
+		t.Errorf("data = %v", got["data"])
+	}
+}
+
+func TestWebhookPublisherRejectedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := NewWebhookPublisher(srv.URL, srv.Client()).Publish(context.Background(), New(TypeLinkCreated, "/s", "", nil))
+	if err == nil {
+		t.Error("Publish succeeded on a 500 response")
+	}
+}
+
+func TestNewPublisher(t *testing.T) {
+	if p, err := NewPublisher(config.EventsConfig{Sink: "none"}, http.DefaultClient); err != nil {
+		t.Errorf("none: %v", err)
+	} else if _, ok := p.(NopPublisher); !ok {
+		t.Errorf("none: got %T", p)
+	}
+	if p, err := NewPublisher(config.EventsConfig{Sink: "webhook", WebhookURL: "https://hooks.example.com"}, http.DefaultClient); err != nil {
+		t.Errorf("webhook: %v", err)
+	} else if _, ok := p.(*WebhookPublisher); !ok {
+		t.Errorf("webhook: got %T", p)
+	}
+	if _, err := NewPublisher(config.EventsConfig{Sink: "webhook"}, http.DefaultClient); err == nil {
+		t.Error("webhook without URL: no error")
+	}
+	if _, err := NewPublisher(config.EventsConfig{Sink: "kafka"}, http.DefaultClient); err == nil {
+		t.Error("unknown sink: no error")
+	}
+}
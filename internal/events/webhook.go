@@ -0,0 +1,47 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ContentType is the media type of a structured-mode CloudEvent.
+const ContentType = "application/cloudevents+json"
+
+// WebhookPublisher POSTs each event to a URL in structured mode.
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher returns a WebhookPublisher sending to url with
+// client.
+func NewWebhookPublisher(url string, client *http.Client) *WebhookPublisher {
+	return &WebhookPublisher{url: url, client: client}
+}
+
+// Publish implements Publisher. Any non-2xx response is an error.
+func (p *WebhookPublisher) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver event %s: %w", e.ID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook rejected event %s: status %d", e.ID, resp.StatusCode)
+	}
+	return nil
+}
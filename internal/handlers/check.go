@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/linkcheck"
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/reqctx"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// LinkLister lists a user's links. *repository.PostgresRepo implements it.
+type LinkLister interface {
+	ListUserShortLinks(ctx context.Context, userID int64, codes []string, limit int) ([]models.ShortLink, error)
+}
+
+// DestinationChecker checks link destinations. *linkcheck.Checker
+// implements it.
+type DestinationChecker interface {
+	Check(ctx context.Context, urls []string) []linkcheck.Result
+}
+
+// CheckHandler serves the destination liveness check.
+type CheckHandler struct {
+	repo     LinkLister
+	checker  DestinationChecker
+	maxLinks int
+}
+
+// NewCheckHandler returns a CheckHandler that checks at most maxLinks links
+// per call.
+func NewCheckHandler(repo LinkLister, checker DestinationChecker, maxLinks int) *CheckHandler {
+	return &CheckHandler{repo: repo, checker: checker, maxLinks: maxLinks}
+}
+
+// CheckLinks handles POST /api/v1/me/links/check. It checks the caller's
+// links named in the optional codes list, or their newest links up to the
+// cap, and reports each destination's status.
+func (h *CheckHandler) CheckLinks(c *gin.Context) {
+	user, ok := reqctx.UserFromContext(c.Request.Context())
+	if !ok {
+		respond.JSON(c, http.StatusUnauthorized, models.Response{Error: "authentication required"})
+		return
+	}
+
+	var req models.CheckLinksRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: bindErrorMessage(err)})
+		return
+	}
+	if len(req.Codes) > h.maxLinks {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: fmt.Sprintf("at most %d codes can be checked per call", h.maxLinks)})
+		return
+	}
+
+	links, err := h.repo.ListUserShortLinks(c.Request.Context(), user.ID, req.Codes, h.maxLinks)
+	if err != nil {
+		storageFailure(c, err, "failed to list short links")
+		return
+	}
+
+	urls := make([]string, len(links))
+	for i, l := range links {
+		urls[i] = l.OriginalURL
+	}
+	checked := h.checker.Check(c.Request.Context(), urls)
+
+	results := make([]models.LinkCheckResult, len(links))
+	for i, l := range links {
+		results[i] = models.LinkCheckResult{
+			Code:        l.Code,
+			OriginalURL: l.OriginalURL,
+			Status:      string(checked[i].Status),
+			HTTPStatus:  checked[i].HTTPStatus,
+		}
+	}
+	respond.JSON(c, http.StatusOK, models.Response{Success: true, Data: results})
+}
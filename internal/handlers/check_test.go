@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/linkcheck"
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+type fakeLister struct {
+	links     []models.ShortLink
+	gotUserID int64
+	gotCodes  []string
+	gotLimit  int
+}
+
+func (f *fakeLister) ListUserShortLinks(_ context.Context, userID int64, codes []string, limit int) ([]models.ShortLink, error) {
+	f.gotUserID, f.gotCodes, f.gotLimit = userID, codes, limit
+	return f.links, nil
+}
+
+type fakeChecker map[string]linkcheck.Result
+
+func (f fakeChecker) Check(_ context.Context, urls []string) []linkcheck.Result {
+	results := make([]linkcheck.Result, len(urls))
+	for i, u := range urls {
+		results[i] = f[u]
+	}
+	return results
+}
+
+func postCheck(repo LinkLister, checker DestinationChecker, user *reqctx.User, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if user != nil {
+		r.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(reqctx.WithUser(c.Request.Context(), *user))
+		})
+	}
+	r.POST("/check", NewCheckHandler(repo, checker, 3).CheckLinks)
+
+	req := httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCheckLinks(t *testing.T) {
+	repo := &fakeLister{links: []models.ShortLink{
+		{Code: "a", OriginalURL: "https://a.example"},
+		{Code: "b", OriginalURL: "https://b.example"},
+		{Code: "c", OriginalURL: "https://c.example"},
+	}}
+	checker := fakeChecker{
+		"https://a.example": {Status: linkcheck.StatusOK, HTTPStatus: 200},
+		"https://b.example": {Status: linkcheck.StatusBroken, HTTPStatus: 404},
+		"https://c.example": {Status: linkcheck.StatusTimeout},
+	}
+
+	w := postCheck(repo, checker, &reqctx.User{ID: 7}, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp struct {
+		Data []models.LinkCheckResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := []models.LinkCheckResult{
+		{Code: "a", OriginalURL: "https://a.example", Status: "ok", HTTPStatus: 200},
+		{Code: "b", OriginalURL: "https://b.example", Status: "broken", HTTPStatus: 404},
+		{Code: "c", OriginalURL: "https://c.example", Status: "timeout"},
+	}
+	if len(resp.Data) != len(want) {
+		t.Fatalf("results = %+v", resp.Data)
+	}
+	for i := range want {
+		if resp.Data[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, resp.Data[i], want[i])
+		}
+	}
+	if repo.gotUserID != 7 || repo.gotCodes != nil || repo.gotLimit != 3 {
+		t.Errorf("listed (%d, %v, %d), want (7, nil, 3)", repo.gotUserID, repo.gotCodes, repo.gotLimit)
+	}
+}
+
+func TestCheckLinksSubset(t *testing.T) {
+	repo := &fakeLister{}
+	w := postCheck(repo, fakeChecker{}, &reqctx.User{ID: 7}, `{"codes":["a","b"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if len(repo.gotCodes) != 2 || repo.gotCodes[0] != "a" || repo.gotCodes[1] != "b" {
+		t.Errorf("codes = %v, want [a b]", repo.gotCodes)
+	}
+}
+
+func TestCheckLinksRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     *reqctx.User
+		body     string
+		wantCode int
+	}{
+		{"anonymous", nil, "", http.StatusUnauthorized},
+		{"too many codes", &reqctx.User{ID: 7}, `{"codes":["a","b","c","d"]}`, http.StatusBadRequest},
+		{"malformed json", &reqctx.User{ID: 7}, `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postCheck(&fakeLister{}, fakeChecker{}, tt.user, tt.body)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
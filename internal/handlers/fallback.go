@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// NotFound answers requests that match no route with a JSON 404.
+func NotFound(c *gin.Context) {
+	respond.JSON(c, http.StatusNotFound, models.Response{Error: "route not found"})
+}
+
+// MethodNotAllowed answers requests whose path is routed for other methods
+// only with a JSON 405. Gin has already set the Allow header to those
+// methods when it calls this.
+func MethodNotAllowed(c *gin.Context) {
+	respond.JSON(c, http.StatusMethodNotAllowed, models.Response{Error: "method not allowed"})
+}
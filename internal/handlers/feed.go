@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/reqctx"
+	"github.com/maojcn/shortlink/internal/respond"
+	"github.com/maojcn/shortlink/internal/urlutil"
+)
+
+// maxFeedItems caps the number of links in a feed.
+const maxFeedItems = 50
+
+// feedPath is the path of the feed relative to the service's base URL.
+const feedPath = "api/v1/me/links/feed.xml"
+
+// FeedHandler serves a user's links as an RSS or Atom feed.
+type FeedHandler struct {
+	repo    LinkLister
+	baseURL string
+}
+
+// NewFeedHandler returns a FeedHandler whose links point under baseURL, or
+// under each request's host when baseURL is empty.
+func NewFeedHandler(repo LinkLister, baseURL string) *FeedHandler {
+	return &FeedHandler{repo: repo, baseURL: baseURL}
+}
+
+// LinksFeed handles GET /api/v1/me/links/feed.xml. It responds with an RSS
+// 2.0 feed of the caller's newest links, up to maxFeedItems, or an Atom
+// feed with ?format=atom. Each item links to the short URL and describes
+// the destination.
+func (h *FeedHandler) LinksFeed(c *gin.Context) {
+	user, ok := reqctx.UserFromContext(c.Request.Context())
+	if !ok {
+		respond.JSON(c, http.StatusUnauthorized, models.Response{Error: "authentication required"})
+		return
+	}
+	format := c.DefaultQuery("format", "rss")
+	if format != "rss" && format != "atom" {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: "format must be rss or atom"})
+		return
+	}
+
+	links, err := h.repo.ListUserShortLinks(c.Request.Context(), user.ID, nil, maxFeedItems)
+	if err != nil {
+		storageFailure(c, err, "failed to list short links")
+		return
+	}
+
+	site := strings.TrimSuffix(shortURL(c.Request, h.baseURL, ""), "/")
+	self := shortURL(c.Request, h.baseURL, feedPath)
+	var feed any
+	contentType := "application/rss+xml; charset=utf-8"
+	if format == "atom" {
+		feed = h.atom(c.Request, site, self, links)
+		contentType = "application/atom+xml; charset=utf-8"
+	} else {
+		feed = h.rss(c.Request, site, links)
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		_ = c.Error(err)
+		respond.JSON(c, http.StatusInternalServerError, models.Response{Error: "failed to build feed"})
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), body...))
+}
+
+const feedTitle = "Your short links"
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (h *FeedHandler) rss(r *http.Request, site string, links []models.ShortLink) rssFeed {
+	feed := rssFeed{Version: "2.0", Channel: rssChannel{
+		Title:       feedTitle,
+		Link:        site,
+		Description: "Links you created, newest first",
+	}}
+	for _, l := range links {
+		u := shortURL(r, h.baseURL, l.Code)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       u,
+			Link:        u,
+			Description: urlutil.Display(l.OriginalURL),
+			GUID:        rssGUID{IsPermaLink: true, Value: u},
+			PubDate:     l.CreatedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return feed
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+func (h *FeedHandler) atom(r *http.Request, site, self string, links []models.ShortLink) atomFeed {
+	// Links are newest first, so the feed was last updated by the first.
+	updated := time.Unix(0, 0).UTC()
+	if len(links) > 0 {
+		updated = links[0].CreatedAt.UTC()
+	}
+	feed := atomFeed{
+		Title:   feedTitle,
+		ID:      self,
+		Updated: updated.Format(time.RFC3339),
+		Author:  atomAuthor{Name: "shortlink"},
+		Links:   []atomLink{{Href: self, Rel: "self"}, {Href: site}},
+	}
+	for _, l := range links {
+		u := shortURL(r, h.baseURL, l.Code)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   u,
+			ID:      u,
+			Updated: l.CreatedAt.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: u},
+			Summary: urlutil.Display(l.OriginalURL),
+		})
+	}
+	return feed
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+func getFeed(repo LinkLister, user *reqctx.User, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if user != nil {
+		r.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(reqctx.WithUser(c.Request.Context(), *user))
+		})
+	}
+	r.GET("/feed.xml", NewFeedHandler(repo, "https://sho.rt").LinksFeed)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/feed.xml"+query, nil))
+	return w
+}
+
+var feedLinks = []models.ShortLink{
+	{Code: "new", OriginalURL: "https://example.com/new?a=1&b=2", CreatedAt: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)},
+	{Code: "old", OriginalURL: "https://example.com/old", CreatedAt: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)},
+}
+
+func TestLinksFeedRSS(t *testing.T) {
+	repo := &fakeLister{links: feedLinks}
+	w := getFeed(repo, &reqctx.User{ID: 7}, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusOK, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/rss+xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if repo.gotUserID != 7 || repo.gotLimit != maxFeedItems || repo.gotCodes != nil {
+		t.Errorf("listed user %d codes %v limit %d, want user 7, all codes, limit %d",
+			repo.gotUserID, repo.gotCodes, repo.gotLimit, maxFeedItems)
+	}
+
+	var feed struct {
+		XMLName xml.Name `xml:"rss"`
+		Version string   `xml:"version,attr"`
+		Channel struct {
+			Title string `xml:"title"`
+			Link  string `xml:"link"`
+			Items []struct {
+				Title       string `xml:"title"`
+				Link        string `xml:"link"`
+				Description string `xml:"description"`
+				GUID        string `xml:"guid"`
+				PubDate     string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("decode feed: %v\n%s", err, w.Body)
+	}
+	if feed.Version != "2.0" || feed.Channel.Title == "" || feed.Channel.Link != "https://sho.rt" {
+		t.Errorf("channel = %+v, version %q", feed.Channel, feed.Version)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("items = %+v, want 2", feed.Channel.Items)
+	}
+	item := feed.Channel.Items[0]
+	if item.Link != "https://sho.rt/new" || item.GUID != item.Link {
+		t.Errorf("item link %q guid %q, want https://sho.rt/new", item.Link, item.GUID)
+	}
+	if item.Description != "https://example.com/new?a=1&b=2" {
+		t.Errorf("item description = %q", item.Description)
+	}
+	if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+		t.Errorf("pubDate %q is not RFC 1123: %v", item.PubDate, err)
+	}
+}
+
+func TestLinksFeedAtom(t *testing.T) {
+	w := getFeed(&fakeLister{links: feedLinks}, &reqctx.User{ID: 7}, "?format=atom")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusOK, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/atom+xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	var feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Link    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Summary string `xml:"summary"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("decode feed: %v\n%s", err, w.Body)
+	}
+	if feed.ID != "https://sho.rt/api/v1/me/links/feed.xml" {
+		t.Errorf("feed id = %q", feed.ID)
+	}
+	if feed.Updated != "2026-03-02T10:00:00Z" {
+		t.Errorf("feed updated = %q, want the newest link's time", feed.Updated)
+	}
+	if len(feed.Links) == 0 || feed.Links[0].Rel != "self" || feed.Links[0].Href != feed.ID {
+		t.Errorf("feed links = %+v, want a self link first", feed.Links)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[1].Link.Href != "https://sho.rt/old" || feed.Entries[1].Summary != "https://example.com/old" {
+		t.Errorf("entries = %+v", feed.Entries)
+	}
+}
+
+func TestLinksFeedErrors(t *testing.T) {
+	if w := getFeed(&fakeLister{}, nil, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without a user: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := getFeed(&fakeLister{}, &reqctx.User{ID: 7}, "?format=json"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// defaultHealthTimeout bounds each dependency check.
+const defaultHealthTimeout = 2 * time.Second
+
+// Pinger checks that a dependency is reachable. *repository.PostgresRepo
+// implements it.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	deps    map[string]Pinger
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewHealthHandler returns a HealthHandler whose readiness check pings each
+// of deps, keyed by the name reported in the response.
+func NewHealthHandler(deps map[string]Pinger) *HealthHandler {
+	return &HealthHandler{deps: deps, timeout: defaultHealthTimeout, now: time.Now}
+}
+
+// Live handles GET /health/live and /livez. It responds 200 whenever the
+// process can serve requests and does not touch any dependency.
+func (h *HealthHandler) Live(c *gin.Context) {
+	probeResponse(c, http.StatusOK, models.HealthResponse{Status: "ok"})
+}
+
+// Ready handles GET /health, /health/ready and /readyz. It pings every
+// dependency and responds 200 if all of them answered, or 503 otherwise,
+// with each check's result and latency.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	resp := models.HealthResponse{Status: "ok", Checks: make(map[string]models.HealthCheck, len(h.deps))}
+	for name, dep := range h.deps {
+		check := h.ping(c.Request.Context(), dep)
+		if !check.OK {
+			resp.Status = "unavailable"
+		}
+		resp.Checks[name] = check
+	}
+
+	status := http.StatusOK
+	if resp.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	probeResponse(c, status, resp)
+}
+
+// probeResponse writes body with status, or only status to a HEAD request:
+// load balancers probing with HEAD get the same status GET would without
+// the body.
+func probeResponse(c *gin.Context, status int, body models.HealthResponse) {
+	if c.Request.Method == http.MethodHead {
+		c.Status(status)
+		return
+	}
+	respond.JSON(c, status, body)
+}
+
+func (h *HealthHandler) ping(ctx context.Context, dep Pinger) models.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := h.now()
+	err := dep.Ping(ctx)
+	check := models.HealthCheck{
+		OK:        err == nil,
+		LatencyMS: float64(h.now().Sub(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+type fakePinger struct{ err error }
+
+func (f fakePinger) Ping(context.Context) error { return f.err }
+
+func getHealth(h *HealthHandler, path string) (*httptest.ResponseRecorder, models.HealthResponse) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", h.Ready)
+	r.GET("/health/live", h.Live)
+	r.GET("/health/ready", h.Ready)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var resp models.HealthResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestHealthReady(t *testing.T) {
+	h := NewHealthHandler(map[string]Pinger{"postgres": fakePinger{}})
+	tick := time.Unix(0, 0)
+	h.now = func() time.Time {
+		tick = tick.Add(1500 * time.Microsecond)
+		return tick
+	}
+
+	for _, path := range []string{"/health", "/health/ready"} {
+		w, resp := getHealth(h, path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", path, w.Code, http.StatusOK)
+		}
+		pg := resp.Checks["postgres"]
+		if resp.Status != "ok" || !pg.OK || pg.LatencyMS != 1.5 {
+			t.Errorf("%s: response = %+v", path, resp)
+		}
+	}
+}
+
+func TestHealthReadyDependencyDown(t *testing.T) {
+	h := NewHealthHandler(map[string]Pinger{
+		"postgres": fakePinger{err: errors.New("connection refused")},
+	})
+
+	w, resp := getHealth(h, "/health/ready")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	pg := resp.Checks["postgres"]
+	if resp.Status != "unavailable" || pg.OK || pg.Error != "connection refused" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestHealthLiveIgnoresDependencies(t *testing.T) {
+	h := NewHealthHandler(map[string]Pinger{
+		"postgres": fakePinger{err: errors.New("connection refused")},
+	})
+
+	w, resp := getHealth(h, "/health/live")
+	if w.Code != http.StatusOK || resp.Status != "ok" || resp.Checks != nil {
+		t.Errorf("status = %d, response = %+v; want 200 ok without checks", w.Code, resp)
+	}
+}
+
+func TestHealthHeadMatchesGet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, dep := range map[string]fakePinger{
+		"healthy":   {},
+		"unhealthy": {err: errors.New("connection refused")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := NewHealthHandler(map[string]Pinger{"postgres": dep})
+			r := gin.New()
+			r.GET("/readyz", h.Ready)
+			r.HEAD("/readyz", h.Ready)
+			r.GET("/livez", h.Live)
+			r.HEAD("/livez", h.Live)
+
+			for _, path := range []string{"/readyz", "/livez"} {
+				get, head := httptest.NewRecorder(), httptest.NewRecorder()
+				r.ServeHTTP(get, httptest.NewRequest(http.MethodGet, path, nil))
+				r.ServeHTTP(head, httptest.NewRequest(http.MethodHead, path, nil))
+				if head.Code != get.Code {
+					t.Errorf("%s: HEAD status = %d, GET status = %d", path, head.Code, get.Code)
+				}
+				if head.Body.Len() != 0 {
+					t.Errorf("%s: HEAD body = %q, want none", path, head.Body)
+				}
+				if get.Body.Len() == 0 {
+					t.Errorf("%s: GET body is empty", path)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/importer"
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// maxImportBytes caps the size of an uploaded export.
+const maxImportBytes = 10 << 20
+
+// ImportLinks handles POST /api/v1/links/import?source=bitly&format=csv.
+// The body is a link export from another shortener; source selects which
+// one (only "bitly" so far, and the default) and format is "csv" or
+// "json". Each record is created as by BatchCreateShortLinks, keeping its
+// short code as the custom code and its creation time where the export has
+// one. Records whose code is already taken or reserved are reported in
+// their result, so the import can be reviewed and the rest kept. The
+// number of records is capped like a batch, by checkBatchSize.
+func (h *LinkHandler) ImportLinks(c *gin.Context) {
+	source := c.DefaultQuery("source", importer.SourceBitly)
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	records, err := importer.Parse(source, c.Query("format"), body)
+	if errors.Is(err, importer.ErrUnsupported) {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: "source must be bitly and format csv or json"})
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respond.JSON(c, http.StatusRequestEntityTooLarge, models.Response{Error: fmt.Sprintf("import must be at most %d bytes", maxImportBytes)})
+		return
+	}
+	if err != nil {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: "invalid import file: " + err.Error()})
+		return
+	}
+	if !h.checkBatchSize(c, len(records)) {
+		return
+	}
+
+	batch := make([]batchItem, len(records))
+	for i, r := range records {
+		batch[i] = batchItem{
+			req:       models.CreateShortLinkRequest{OriginalURL: r.LongURL, CustomCode: r.ShortCode},
+			createdAt: r.Created,
+		}
+	}
+	h.createBatch(c, batch)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+const bitlyCSV = `long_url,short_code,created
+https://example.com/launch,launch19,2019-03-01 09:30:00
+https://example.org/docs,taken,2020-07-15 12:00:00
+not a url,bad1,
+https://example.net/,,
+`
+
+func postImport(t *testing.T, repo *fakeLinkRepo, query, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links/import"+query, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	newLinkRouter(repo, &reqctx.User{ID: 7}).ServeHTTP(w, req)
+	return w
+}
+
+func TestImportLinksBitlyCSV(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	w := postImport(t, repo, "?source=bitly&format=csv", bitlyCSV)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	var resp struct {
+		Data []models.BatchItemResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	wantErr := []string{"", "custom_code is already taken", "original_url must be an absolute http or https URL", ""}
+	if len(resp.Data) != len(wantErr) {
+		t.Fatalf("got %d results, want %d", len(resp.Data), len(wantErr))
+	}
+	for i, want := range wantErr {
+		if got := resp.Data[i]; got.Error != want || got.Success != (want == "") {
+			t.Errorf("result %d = %+v, want error %q", i, got, want)
+		}
+	}
+
+	if len(repo.gotBatch) != 3 {
+		t.Fatalf("repository got %d items, want 3", len(repo.gotBatch))
+	}
+	first := repo.gotBatch[0]
+	if first.CustomCode != "launch19" || first.UserID != 7 || first.CreatedAt == nil || !first.CreatedAt.Equal(time.Date(2019, 3, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("first item = %+v, want code and creation time kept", first)
+	}
+	if last := repo.gotBatch[2]; last.CustomCode != "" || last.CreatedAt != nil {
+		t.Errorf("last item = %+v, want generated code and no creation time", last)
+	}
+}
+
+func TestImportLinksBitlyJSON(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	w := postImport(t, repo, "?format=json", `{"links":[{"long_url":"https://example.com","link":"https://bit.ly/abc123"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusOK, w.Body)
+	}
+	if len(repo.gotBatch) != 1 || repo.gotBatch[0].CustomCode != "abc123" {
+		t.Errorf("repository got %+v", repo.gotBatch)
+	}
+}
+
+func TestImportLinksRejectsImport(t *testing.T) {
+	tooMany := "long_url\n" + strings.Repeat("https://example.com\n", defaultMaxBatchItems+1)
+	tests := []struct {
+		name     string
+		query    string
+		body     string
+		wantCode int
+	}{
+		{"unknown source", "?source=tinyurl&format=csv", bitlyCSV, http.StatusBadRequest},
+		{"missing format", "", bitlyCSV, http.StatusBadRequest},
+		{"malformed", "?format=json", "{", http.StatusBadRequest},
+		{"empty", "?format=csv", "long_url\n", http.StatusBadRequest},
+		{"too many", "?format=csv", tooMany, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLinkRepo{}
+			w := postImport(t, repo, tt.query, tt.body)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d; body %s", w.Code, tt.wantCode, w.Body)
+			}
+			if repo.called {
+				t.Error("repository called for a rejected import")
+			}
+		})
+	}
+}
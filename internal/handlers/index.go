@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// apiIndex is the discovery document of /api/v1. It is static, so it must
+// be kept in step with the routes in package server.
+var apiIndex = models.APIIndex{
+	Version: "v1",
+	Collections: []models.APICollection{
+		{Name: "links", Href: "/api/v1/links", Methods: []string{http.MethodPost}},
+		{Name: "links.batch", Href: "/api/v1/links/batch", Methods: []string{http.MethodPost}},
+		{Name: "links.import", Href: "/api/v1/links/import", Methods: []string{http.MethodPost}},
+		{Name: "me.links.check", Href: "/api/v1/me/links/check", Methods: []string{http.MethodPost}},
+		{Name: "me.links.feed", Href: "/api/v1/me/links/feed.xml", Methods: []string{http.MethodGet}},
+		{Name: "me.share-tokens", Href: "/api/v1/me/share-tokens", Methods: []string{http.MethodPost}},
+	},
+}
+
+// APIIndex handles GET /api/v1. It lists the API's collections and needs
+// no credentials, so new integrators can find their way around.
+func APIIndex(c *gin.Context) {
+	respond.JSON(c, http.StatusOK, models.Response{Success: true, Data: apiIndex})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+func TestAPIIndex(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1", APIIndex)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Data models.APIIndex `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.Version != "v1" {
+		t.Errorf("version = %q, want v1", resp.Data.Version)
+	}
+	hrefs := make(map[string]string)
+	for _, col := range resp.Data.Collections {
+		hrefs[col.Name] = col.Href
+	}
+	for name, want := range map[string]string{
+		"links":           "/api/v1/links",
+		"me.share-tokens": "/api/v1/me/share-tokens",
+	} {
+		if hrefs[name] != want {
+			t.Errorf("collection %q href = %q, want %q", name, hrefs[name], want)
+		}
+	}
+}
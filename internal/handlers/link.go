@@ -0,0 +1,410 @@
+// Package handlers contains the Gin HTTP handlers.
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/config"
+	"github.com/maojcn/shortlink/internal/duration"
+	"github.com/maojcn/shortlink/internal/events"
+	"github.com/maojcn/shortlink/internal/logging"
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reqctx"
+	"github.com/maojcn/shortlink/internal/respond"
+	"github.com/maojcn/shortlink/internal/urlutil"
+	"github.com/maojcn/shortlink/internal/validation"
+)
+
+// defaultMaxBatchItems is the batch cap used when
+// LinkConfig.MaxBatchItems is not positive.
+const defaultMaxBatchItems = 1000
+
+const (
+	badURLMessage         = "original_url must be an absolute http or https URL"
+	badCodeMessage        = "custom_code must be 3-32 letters, digits, '_' or '-'"
+	confusableHostMessage = "original_url host looks like it may imitate another domain"
+	reservedCodeMessage   = "custom_code is reserved"
+)
+
+// LinkRepository is the storage LinkHandler needs. *repository.PostgresRepo
+// implements it.
+type LinkRepository interface {
+	CreateShortLink(ctx context.Context, in models.NewShortLink) (*models.ShortLink, error)
+	BatchCreateShortLinks(ctx context.Context, items []models.NewShortLink) ([]repository.BatchResult, error)
+}
+
+// ReservedCodes reports codes that may not be chosen as custom codes.
+// *reservedcodes.Set implements it.
+type ReservedCodes interface {
+	Contains(code string) bool
+}
+
+// LinkHandler serves the short link endpoints.
+type LinkHandler struct {
+	repo         LinkRepository
+	publisher    events.Publisher
+	reserved     ReservedCodes
+	eventSource  string
+	links        config.LinkConfig
+	maxBatch     int
+	batchWorkers int
+	baseURL      string
+	now          func() time.Time
+}
+
+// NewLinkHandler returns a LinkHandler backed by repo. Lifecycle events are
+// sent to publisher with cfg.Events.Source as their CloudEvents source.
+// Custom codes in reserved are refused; reserved may be nil. Batch
+// endpoints accept at most cfg.Links.MaxBatchItems links per request and
+// validate them on cfg.Links.BatchWorkers goroutines.
+// The custom validator tags the request structs use are registered with
+// gin here.
+func NewLinkHandler(repo LinkRepository, publisher events.Publisher, reserved ReservedCodes, cfg *config.Config) *LinkHandler {
+	validation.RegisterGin()
+	maxBatch := cfg.Links.MaxBatchItems
+	if maxBatch <= 0 {
+		maxBatch = defaultMaxBatchItems
+	}
+	return &LinkHandler{
+		repo:         repo,
+		publisher:    publisher,
+		reserved:     reserved,
+		eventSource:  cfg.Events.Source,
+		links:        cfg.Links,
+		maxBatch:     maxBatch,
+		batchWorkers: max(cfg.Links.BatchWorkers, 1),
+		baseURL:      cfg.Server.BaseURL,
+		now:          time.Now,
+	}
+}
+
+// CreateShortLink handles POST /api/v1/links. It responds 201 with the
+// created link, whose code can be used immediately, or 409 if the
+// requested custom_code is taken, reserved by the operator or in a prefix
+// reserved by another user.
+// An expires_in lifetime sets the link's expires_at. The response's
+// short_url is the code under ServerConfig.BaseURL, or under the request's
+// host when no base URL is configured.
+//
+// The destination host is stored in punycode and echoed in Unicode as
+// display_url. A host that looks like a homograph of another domain is
+// rejected when LinkConfig.RejectConfusableHosts is set and otherwise
+// reported in the response's warnings.
+//
+// The body is JSON unless it is sent as application/x-www-form-urlencoded
+// or multipart/form-data, in which case the same fields are read from the
+// form. Validation and the JSON response are the same either way.
+//
+// A link.created event is published once the link is stored; a publishing
+// failure is logged with the request's logger and does not fail the
+// request.
+func (h *LinkHandler) CreateShortLink(c *gin.Context) {
+	var req models.CreateShortLinkRequest
+	if problem := bindCreateRequest(c, &req); problem != "" {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: problem})
+		return
+	}
+	in, warnings, problem := h.newShortLink(c.Request.Context(), req)
+	if problem != "" {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: problem})
+		return
+	}
+	if h.isReserved(in.CustomCode) {
+		respond.JSON(c, http.StatusConflict, models.Response{Error: reservedCodeMessage})
+		return
+	}
+
+	link, err := h.repo.CreateShortLink(c.Request.Context(), in)
+	if msg := conflictMessage(err); msg != "" {
+		respond.JSON(c, http.StatusConflict, models.Response{Error: msg})
+		return
+	}
+	if err != nil {
+		storageFailure(c, err, "failed to create short link")
+		return
+	}
+
+	h.created(c, link)
+	respond.JSON(c, http.StatusCreated, models.Response{Success: true, Data: link, Warnings: warnings})
+}
+
+// BatchCreateShortLinks handles POST /api/v1/links/batch. The body is an
+// array of create requests, within checkBatchSize's limits. Every item is
+// validated and created as by CreateShortLink, in one transaction, and the
+// response holds one result per item in request order. Items rejected for their input or
+// custom code are reported in their result without affecting the others;
+// only a storage failure fails the whole batch, with 500.
+func (h *LinkHandler) BatchCreateShortLinks(c *gin.Context) {
+	var reqs []models.CreateShortLinkRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqs); err != nil {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: "invalid JSON body"})
+		return
+	}
+	if !h.checkBatchSize(c, len(reqs)) {
+		return
+	}
+
+	batch := make([]batchItem, len(reqs))
+	for i, req := range reqs {
+		batch[i].req = req
+	}
+	h.createBatch(c, batch)
+}
+
+// checkBatchSize reports whether a batch endpoint may process n links. If
+// not, it has responded 400 for an empty batch or 413 for one over the
+// configured cap, naming the cap.
+func (h *LinkHandler) checkBatchSize(c *gin.Context, n int) bool {
+	switch {
+	case n == 0:
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: "batch must contain at least one link"})
+		return false
+	case n > h.maxBatch:
+		respond.JSON(c, http.StatusRequestEntityTooLarge, models.Response{Error: fmt.Sprintf("batch must contain at most %d links", h.maxBatch)})
+		return false
+	default:
+		return true
+	}
+}
+
+// batchItem is one link of a batch create or import. A non-nil createdAt
+// is kept as the link's creation time.
+type batchItem struct {
+	req       models.CreateShortLinkRequest
+	createdAt *time.Time
+}
+
+// createBatch validates and creates batch as BatchCreateShortLinks
+// describes and writes the per-item results.
+func (h *LinkHandler) createBatch(c *gin.Context, batch []batchItem) {
+	results := make([]models.BatchItemResult, len(batch))
+	var items []models.NewShortLink
+	var positions []int
+	for i, p := range h.prepareBatch(c.Request.Context(), batch) {
+		results[i].Index = i
+		results[i].Warnings = p.warnings
+		if p.problem != "" {
+			results[i].Error = p.problem
+			continue
+		}
+		items = append(items, p.in)
+		positions = append(positions, i)
+	}
+
+	if len(items) > 0 {
+		created, err := h.repo.BatchCreateShortLinks(c.Request.Context(), items)
+		if err != nil {
+			storageFailure(c, err, "failed to create short links")
+			return
+		}
+		for j, r := range created {
+			res := &results[positions[j]]
+			if r.Err != nil {
+				res.Error = conflictMessage(r.Err)
+				continue
+			}
+			h.created(c, r.Link)
+			res.Success, res.Data = true, r.Link
+		}
+	}
+	respond.JSON(c, http.StatusOK, models.Response{Success: true, Data: results})
+}
+
+// preparedItem is a batch item checked and turned into repository input.
+// A non-empty problem is why it was rejected.
+type preparedItem struct {
+	in       models.NewShortLink
+	warnings []string
+	problem  string
+}
+
+// prepareBatch validates every item of batch and returns the outcomes in
+// the same order. The items are independent, so they are spread over a
+// pool of at most LinkConfig.BatchWorkers goroutines; the pool, not the
+// batch size, bounds the work in flight.
+func (h *LinkHandler) prepareBatch(ctx context.Context, batch []batchItem) []preparedItem {
+	prepared := make([]preparedItem, len(batch))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(h.batchWorkers, len(batch)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				prepared[i] = h.prepareItem(ctx, batch[i])
+			}
+		}()
+	}
+	for i := range batch {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return prepared
+}
+
+func (h *LinkHandler) prepareItem(ctx context.Context, item batchItem) preparedItem {
+	if err := binding.Validator.ValidateStruct(&item.req); err != nil {
+		return preparedItem{problem: bindErrorMessage(err)}
+	}
+	in, warnings, problem := h.newShortLink(ctx, item.req)
+	if problem == "" && h.isReserved(in.CustomCode) {
+		problem = reservedCodeMessage
+	}
+	in.CreatedAt = item.createdAt
+	return preparedItem{in: in, warnings: warnings, problem: problem}
+}
+
+// newShortLink turns req, already checked against its binding tags, into
+// the repository input. A scheme-less destination first gets
+// LinkConfig.DefaultScheme unless LinkConfig.StrictScheme is set; the
+// result must then be an absolute http(s) URL. A non-empty problem is the
+// client-facing reason req was rejected.
+func (h *LinkHandler) newShortLink(ctx context.Context, req models.CreateShortLinkRequest) (in models.NewShortLink, warnings []string, problem string) {
+	original := req.OriginalURL
+	if !h.links.StrictScheme && h.links.DefaultScheme != "" {
+		original = urlutil.EnsureScheme(original, h.links.DefaultScheme)
+	}
+	if !isHTTPURL(original) {
+		return in, nil, badURLMessage
+	}
+	normalized, err := urlutil.Normalize(original)
+	if err != nil {
+		return in, nil, "original_url has an invalid host"
+	}
+	if u, _ := url.Parse(normalized); urlutil.IsConfusable(u.Hostname()) {
+		if h.links.RejectConfusableHosts {
+			return in, nil, confusableHostMessage
+		}
+		warnings = append(warnings, confusableHostMessage)
+	}
+
+	in = models.NewShortLink{OriginalURL: normalized, CustomCode: req.CustomCode, Description: strings.TrimSpace(req.Description)}
+	if req.ExpiresIn != "" {
+		ttl, err := duration.Parse(req.ExpiresIn)
+		if err != nil {
+			return in, warnings, "expires_in must be a positive duration such as 24h, 7d or 3mo"
+		}
+		expiresAt := h.now().Add(ttl)
+		in.ExpiresAt = &expiresAt
+	}
+	if u, ok := reqctx.UserFromContext(ctx); ok {
+		in.UserID = u.ID
+	}
+	return in, warnings, ""
+}
+
+// created fills in the link's short and display URLs and publishes
+// link.created.
+func (h *LinkHandler) created(c *gin.Context, link *models.ShortLink) {
+	link.ShortURL = shortURL(c.Request, h.baseURL, link.Code)
+	if display := urlutil.Display(link.OriginalURL); display != link.OriginalURL {
+		link.DisplayURL = display
+	}
+	e := events.New(events.TypeLinkCreated, h.eventSource, link.Code, link)
+	if err := h.publisher.Publish(c.Request.Context(), e); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("failed to publish event",
+			zap.String("event_id", e.ID), zap.String("type", e.Type), zap.String("code", link.Code), zap.Error(err))
+	}
+}
+
+// isReserved reports whether the operator has reserved the custom code.
+func (h *LinkHandler) isReserved(code string) bool {
+	return code != "" && h.reserved != nil && h.reserved.Contains(code)
+}
+
+// conflictMessage returns the client-facing message for a custom code the
+// repository refused, or "" if err is not such a refusal.
+func conflictMessage(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrCodeTaken):
+		return "custom_code is already taken"
+	case errors.Is(err, repository.ErrPrefixReserved):
+		return "custom_code uses a prefix reserved by another user"
+	default:
+		return ""
+	}
+}
+
+// bindCreateRequest binds a create request from a form or JSON body,
+// chosen by Content-Type. A non-empty problem is the client-facing reason
+// it could not be bound.
+func bindCreateRequest(c *gin.Context, req *models.CreateShortLinkRequest) (problem string) {
+	switch c.ContentType() {
+	case binding.MIMEPOSTForm, binding.MIMEMultipartPOSTForm:
+		if err := c.ShouldBindWith(req, binding.Form); err != nil {
+			var verrs validator.ValidationErrors
+			if !errors.As(err, &verrs) {
+				return "invalid form body"
+			}
+			return bindErrorMessage(err)
+		}
+		return ""
+	default:
+		if err := c.ShouldBindJSON(req); err != nil {
+			return bindErrorMessage(err)
+		}
+		return ""
+	}
+}
+
+// bindErrorMessage turns a binding error into a client-facing message:
+// validation failures name the offending field, anything else is a body
+// that could not be decoded.
+func bindErrorMessage(err error) string {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) && len(verrs) > 0 {
+		fe := verrs[0]
+		switch fe.Tag() {
+		case "required":
+			return jsonFieldName(fe) + " is required"
+		case validation.TagSafeURL:
+			return badURLMessage
+		case validation.TagShortCode:
+			return badCodeMessage
+		case "max":
+			return fmt.Sprintf("%s must be at most %s characters", jsonFieldName(fe), fe.Param())
+		}
+		return jsonFieldName(fe) + " is invalid"
+	}
+	return "invalid JSON body"
+}
+
+// jsonFieldName maps a validation error to the request's JSON field name.
+func jsonFieldName(fe validator.FieldError) string {
+	switch fe.Field() {
+	case "OriginalURL":
+		return "original_url"
+	case "CustomCode":
+		return "custom_code"
+	case "Description":
+		return "description"
+	case "Scope":
+		return "scope"
+	default:
+		return fe.Field()
+	}
+}
+
+// isHTTPURL reports whether raw is an absolute http(s) URL with a host.
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,799 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/maojcn/shortlink/internal/config"
+	"github.com/maojcn/shortlink/internal/events"
+	"github.com/maojcn/shortlink/internal/logging"
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reqctx"
+	"github.com/maojcn/shortlink/internal/shortcode"
+)
+
+type fakeLinkRepo struct {
+	err      error
+	got      models.NewShortLink
+	gotBatch []models.NewShortLink
+	called   bool
+}
+
+// BatchCreateShortLinks fails with f.err, and otherwise treats the custom
+// code "taken" as already in use.
+func (f *fakeLinkRepo) BatchCreateShortLinks(_ context.Context, items []models.NewShortLink) ([]repository.BatchResult, error) {
+	f.called, f.gotBatch = true, items
+	if f.err != nil {
+		return nil, f.err
+	}
+	results := make([]repository.BatchResult, len(items))
+	for i, in := range items {
+		if in.CustomCode == "taken" {
+			results[i].Err = repository.ErrCodeTaken
+			continue
+		}
+		results[i].Link = &models.ShortLink{ID: int64(i + 1), Code: shortcode.Encode(int64(i + 1)), OriginalURL: in.OriginalURL, UserID: in.UserID}
+	}
+	return results, nil
+}
+
+func (f *fakeLinkRepo) CreateShortLink(_ context.Context, in models.NewShortLink) (*models.ShortLink, error) {
+	f.called, f.got = true, in
+	if f.err != nil {
+		return nil, f.err
+	}
+	code := in.CustomCode
+	if code == "" {
+		code = "10"
+	}
+	return &models.ShortLink{ID: 62, Code: code, OriginalURL: in.OriginalURL, UserID: in.UserID, ExpiresAt: in.ExpiresAt, Description: in.Description}, nil
+}
+
+type recordingPublisher struct {
+	events []events.Event
+	err    error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, e events.Event) error {
+	p.events = append(p.events, e)
+	return p.err
+}
+
+var testLinkConfig = &config.Config{Events: config.EventsConfig{Source: "/test"}}
+
+func newLinkRouter(repo LinkRepository, user *reqctx.User) *gin.Engine {
+	return newLinkRouterWithPublisher(repo, user, events.NopPublisher{})
+}
+
+func newLinkRouterWithPublisher(repo LinkRepository, user *reqctx.User, pub events.Publisher) *gin.Engine {
+	return newLinkRouterWithHandler(NewLinkHandler(repo, pub, nil, testLinkConfig), user)
+}
+
+func newLinkRouterWithHandler(h *LinkHandler, user *reqctx.User) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if user != nil {
+		r.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(reqctx.WithUser(c.Request.Context(), *user))
+		})
+	}
+	r.POST("/api/v1/links", h.CreateShortLink)
+	r.POST("/api/v1/links/batch", h.BatchCreateShortLinks)
+	r.POST("/api/v1/links/import", h.ImportLinks)
+	return r
+}
+
+func postLink(r http.Handler, body string) *httptest.ResponseRecorder {
+	return postJSON(r, "/api/v1/links", body)
+}
+
+func postJSON(r http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateShortLinkReturnsCode(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	w := postLink(newLinkRouter(repo, &reqctx.User{ID: 7}), `{"original_url":"https://example.com/a?b=c"}`)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var resp struct {
+		Success bool             `json:"success"`
+		Data    models.ShortLink `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Success || resp.Data.Code != "10" {
+		t.Errorf("response = %+v, want success with code %q", resp, "10")
+	}
+	if resp.Data.ShortURL != "http://example.com/10" {
+		t.Errorf("short_url = %q, want it derived from the request host", resp.Data.ShortURL)
+	}
+	if repo.got.OriginalURL != "https://example.com/a?b=c" || repo.got.UserID != 7 {
+		t.Errorf("repo called with (%q, %d), want (%q, 7)", repo.got.OriginalURL, repo.got.UserID, "https://example.com/a?b=c")
+	}
+}
+
+func TestCreateShortLinkFormEncoded(t *testing.T) {
+	form := url.Values{"original_url": {"https://example.com/a"}, "custom_code": {"form-link"}, "expires_in": {"1h"}}
+	for _, tt := range []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"json", "application/json", `{"original_url":"https://example.com/a","custom_code":"form-link","expires_in":"1h"}`},
+		{"form", "application/x-www-form-urlencoded", form.Encode()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLinkRepo{}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			newLinkRouter(repo, nil).ServeHTTP(w, req)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusCreated, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", ct)
+			}
+			if got := repo.got; got.OriginalURL != "https://example.com/a" || got.CustomCode != "form-link" || got.ExpiresAt == nil {
+				t.Errorf("repo got %+v", got)
+			}
+		})
+	}
+}
+
+func TestCreateShortLinkFormEncodedValidation(t *testing.T) {
+	for body, wantErr := range map[string]string{
+		"custom_code=abc":                                      "original_url is required",
+		"original_url=ftp%3A%2F%2Fexample.com":                 "original_url must be an absolute http or https URL",
+		"original_url=https%3A%2F%2Fexample.com&custom_code=a": "custom_code must be 3-32 letters, digits, '_' or '-'",
+	} {
+		repo := &fakeLinkRepo{}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		newLinkRouter(repo, nil).ServeHTTP(w, req)
+
+		var resp models.Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decode response: %v", body, err)
+		}
+		if w.Code != http.StatusBadRequest || resp.Error != wantErr {
+			t.Errorf("%s: got %d %q, want 400 %q", body, w.Code, resp.Error, wantErr)
+		}
+		if repo.called {
+			t.Errorf("%s: repository called", body)
+		}
+	}
+}
+
+func TestBatchCreateShortLinksUsesBaseURL(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{BaseURL: "https://sho.rt"}}
+	h := NewLinkHandler(&fakeLinkRepo{}, events.NopPublisher{}, nil, cfg)
+	w := postJSON(newLinkRouterWithHandler(h, nil), "/api/v1/links/batch",
+		`[{"original_url":"https://example.com"},{"original_url":"https://example.org"}]`)
+
+	var resp struct {
+		Data []models.BatchItemResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Data) != 2 || resp.Data[0].Data == nil || resp.Data[1].Data == nil {
+		t.Fatalf("results = %+v", resp.Data)
+	}
+	for i, want := range []string{"https://sho.rt/1", "https://sho.rt/2"} {
+		if got := resp.Data[i].Data.ShortURL; got != want {
+			t.Errorf("item %d: short_url = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestCreateShortLinkSchemePrefixing(t *testing.T) {
+	tests := []struct {
+		name    string
+		links   config.LinkConfig
+		input   string
+		wantURL string // "" means rejected
+	}{
+		{"scheme-less", config.LinkConfig{DefaultScheme: "https"}, "example.com/a", "https://example.com/a"},
+		{"scheme-less with port", config.LinkConfig{DefaultScheme: "http"}, "example.com:8080", "http://example.com:8080"},
+		{"already schemed", config.LinkConfig{DefaultScheme: "https"}, "http://example.com", "http://example.com"},
+		{"ambiguous", config.LinkConfig{DefaultScheme: "https"}, "localhost/a", ""},
+		{"other scheme", config.LinkConfig{DefaultScheme: "https"}, "ftp://example.com", ""},
+		{"strict", config.LinkConfig{DefaultScheme: "https", StrictScheme: true}, "example.com/a", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLinkRepo{}
+			h := NewLinkHandler(repo, events.NopPublisher{}, nil, &config.Config{Links: tt.links})
+			w := postLink(newLinkRouterWithHandler(h, nil), `{"original_url":"`+tt.input+`"}`)
+			if tt.wantURL == "" {
+				if w.Code != http.StatusBadRequest || repo.called {
+					t.Errorf("status = %d, repository called = %v; want rejection", w.Code, repo.called)
+				}
+				return
+			}
+			if w.Code != http.StatusCreated || repo.got.OriginalURL != tt.wantURL {
+				t.Errorf("status = %d, stored %q; want 201 and %q", w.Code, repo.got.OriginalURL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestCreateShortLinkAnonymous(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	w := postLink(newLinkRouter(repo, nil), `{"original_url":"http://example.com"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if repo.got.UserID != 0 {
+		t.Errorf("userID = %d, want 0", repo.got.UserID)
+	}
+}
+
+func TestCreateShortLinkRejectsBadInput(t *testing.T) {
+	const (
+		badURL    = "original_url must be an absolute http or https URL"
+		badCode   = "custom_code must be 3-32 letters, digits, '_' or '-'"
+		badExpiry = "expires_in must be a positive duration such as 24h, 7d or 3mo"
+	)
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"malformed json", `{`, "invalid JSON body"},
+		{"wrong type", `{"original_url":42}`, "invalid JSON body"},
+		{"missing url", `{}`, "original_url is required"},
+		{"ftp scheme", `{"original_url":"ftp://example.com/file"}`, badURL},
+		{"javascript scheme", `{"original_url":"javascript:alert(1)"}`, badURL},
+		{"relative", `{"original_url":"/just/a/path"}`, badURL},
+		{"no scheme", `{"original_url":"example.com"}`, badURL},
+		{"no host", `{"original_url":"https://"}`, badURL},
+		{"custom code too short", `{"original_url":"https://example.com","custom_code":"ab"}`, badCode},
+		{"custom code too long", `{"original_url":"https://example.com","custom_code":"` + strings.Repeat("a", 33) + `"}`, badCode},
+		{"custom code with slash", `{"original_url":"https://example.com","custom_code":"a/b/c"}`, badCode},
+		{"invalid idn host", `{"original_url":"https://-bad-.com"}`, "original_url has an invalid host"},
+		{"unparseable expiry", `{"original_url":"https://example.com","expires_in":"soon"}`, badExpiry},
+		{"negative expiry", `{"original_url":"https://example.com","expires_in":"-1h"}`, badExpiry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLinkRepo{}
+			w := postLink(newLinkRouter(repo, nil), tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			var resp models.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Error != tt.wantErr {
+				t.Errorf("error = %q, want %q", resp.Error, tt.wantErr)
+			}
+			if repo.called {
+				t.Error("repository was called for invalid input")
+			}
+		})
+	}
+}
+
+func TestCreateShortLinkCustomCode(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	w := postLink(newLinkRouter(repo, nil), `{"original_url":"https://example.com","custom_code":"Spring_Sale-26"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if repo.got.CustomCode != "Spring_Sale-26" {
+		t.Errorf("custom code = %q, want %q", repo.got.CustomCode, "Spring_Sale-26")
+	}
+}
+
+func TestCreateShortLinkExpiresIn(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	repo := &fakeLinkRepo{}
+	h := NewLinkHandler(repo, events.NopPublisher{}, nil, testLinkConfig)
+	h.now = func() time.Time { return now }
+
+	w := postLink(newLinkRouterWithHandler(h, nil), `{"original_url":"https://example.com","expires_in":"7d"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusCreated, w.Body)
+	}
+	want := now.Add(7 * 24 * time.Hour)
+	if repo.got.ExpiresAt == nil || !repo.got.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", repo.got.ExpiresAt, want)
+	}
+	var resp struct {
+		Data models.ShortLink `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.ExpiresAt == nil || !resp.Data.ExpiresAt.Equal(want) {
+		t.Errorf("response expires_at = %v, want %v", resp.Data.ExpiresAt, want)
+	}
+}
+
+func TestCreateShortLinkDescription(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	w := postLink(newLinkRouter(repo, nil), `{"original_url":"https://example.com","description":"  Spring campaign, newsletter  "}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if repo.got.Description != "Spring campaign, newsletter" {
+		t.Errorf("description = %q, want it trimmed", repo.got.Description)
+	}
+	var resp struct {
+		Data models.ShortLink `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.Description != "Spring campaign, newsletter" {
+		t.Errorf("response description = %q", resp.Data.Description)
+	}
+}
+
+func TestCreateShortLinkDescriptionLength(t *testing.T) {
+	// The limit counts characters, not bytes.
+	for _, tt := range []struct {
+		desc     string
+		wantCode int
+	}{
+		{strings.Repeat("é", 500), http.StatusCreated},
+		{strings.Repeat("a", 501), http.StatusBadRequest},
+	} {
+		repo := &fakeLinkRepo{}
+		w := postLink(newLinkRouter(repo, nil), `{"original_url":"https://example.com","description":"`+tt.desc+`"}`)
+		if w.Code != tt.wantCode {
+			t.Errorf("%d characters: status = %d, want %d; body %s", len([]rune(tt.desc)), w.Code, tt.wantCode, w.Body)
+		}
+		if tt.wantCode != http.StatusBadRequest {
+			continue
+		}
+		var resp models.Response
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Error != "description must be at most 500 characters" || repo.called {
+			t.Errorf("error = %q, repository called %v", resp.Error, repo.called)
+		}
+	}
+}
+
+func TestCreateShortLinkWithoutExpiry(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	postLink(newLinkRouter(repo, nil), `{"original_url":"https://example.com"}`)
+	if repo.got.ExpiresAt != nil {
+		t.Errorf("ExpiresAt = %v, want nil", repo.got.ExpiresAt)
+	}
+}
+
+func TestCreateShortLinkNormalizesIDNHost(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	w := postLink(newLinkRouter(repo, nil), `{"original_url":"https://MÜNCHEN.de/stadt"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if repo.got.OriginalURL != "https://xn--mnchen-3ya.de/stadt" {
+		t.Errorf("stored %q, want punycode host", repo.got.OriginalURL)
+	}
+	var resp struct {
+		Data     models.ShortLink `json:"data"`
+		Warnings []string         `json:"warnings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.DisplayURL != "https://münchen.de/stadt" {
+		t.Errorf("display_url = %q, want %q", resp.Data.DisplayURL, "https://münchen.de/stadt")
+	}
+	if len(resp.Warnings) != 0 {
+		t.Errorf("warnings = %v, want none", resp.Warnings)
+	}
+}
+
+func TestCreateShortLinkConfusableHost(t *testing.T) {
+	const body = `{"original_url":"https://pаypal.com/login"}` // Cyrillic а
+
+	repo := &fakeLinkRepo{}
+	w := postLink(newLinkRouter(repo, nil), body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("warn mode: status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	var resp models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Errorf("warn mode: warnings = %v, want one", resp.Warnings)
+	}
+
+	cfg := *testLinkConfig
+	cfg.Links.RejectConfusableHosts = true
+	repo = &fakeLinkRepo{}
+	w = postLink(newLinkRouterWithHandler(NewLinkHandler(repo, events.NopPublisher{}, nil, &cfg), nil), body)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("reject mode: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if repo.called {
+		t.Error("reject mode: repository was called")
+	}
+}
+
+func TestCreateShortLinkCustomCodeTaken(t *testing.T) {
+	repo := &fakeLinkRepo{err: repository.ErrCodeTaken}
+	w := postLink(newLinkRouter(repo, nil), `{"original_url":"https://example.com","custom_code":"promo"}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestCreateShortLinkPublishesEvent(t *testing.T) {
+	pub := &recordingPublisher{}
+	w := postLink(newLinkRouterWithPublisher(&fakeLinkRepo{}, nil, pub), `{"original_url":"https://example.com"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("published %d events, want 1", len(pub.events))
+	}
+	e := pub.events[0]
+	if e.Type != events.TypeLinkCreated || e.Source != "/test" || e.Subject != "10" {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestCreateShortLinkPublishFailureStillCreates(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	pub := &recordingPublisher{err: errors.New("webhook down")}
+	h := NewLinkHandler(&fakeLinkRepo{}, pub, nil, testLinkConfig)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx := logging.WithLogger(c.Request.Context(), zap.New(core))
+		c.Request = c.Request.WithContext(reqctx.WithRequestID(ctx, "req-1"))
+	})
+	r.POST("/api/v1/links", h.CreateShortLink)
+	w := postLink(r, `{"original_url":"https://example.com"}`)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].ContextMap()["request_id"] != "req-1" {
+		t.Errorf("log entries = %v, want one warning with the request ID", entries)
+	}
+}
+
+type reservedSet map[string]bool
+
+func (r reservedSet) Contains(code string) bool { return r[code] }
+
+func TestCreateShortLinkReservedCode(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	h := NewLinkHandler(repo, events.NopPublisher{}, reservedSet{"brand": true}, testLinkConfig)
+	r := newLinkRouterWithHandler(h, nil)
+
+	w := postLink(r, `{"original_url":"https://example.com","custom_code":"brand"}`)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), reservedCodeMessage) {
+		t.Errorf("status = %d, body %s; want 409 reserved", w.Code, w.Body)
+	}
+	if repo.called {
+		t.Error("repository called for a reserved code")
+	}
+
+	w = postJSON(r, "/api/v1/links/batch", `[{"original_url":"https://example.com","custom_code":"brand"},{"original_url":"https://example.com","custom_code":"mine"}]`)
+	var resp struct {
+		Data []models.BatchItemResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Data) != 2 || resp.Data[0].Error != reservedCodeMessage || !resp.Data[1].Success {
+		t.Errorf("batch results = %+v", resp.Data)
+	}
+	if len(repo.gotBatch) != 1 || repo.gotBatch[0].CustomCode != "mine" {
+		t.Errorf("batch sent to repository = %+v, want only the unreserved item", repo.gotBatch)
+	}
+}
+
+func TestCreateShortLinkReservedPrefix(t *testing.T) {
+	repo := &fakeLinkRepo{err: repository.ErrPrefixReserved}
+	w := postLink(newLinkRouter(repo, &reqctx.User{ID: 8}), `{"original_url":"https://example.com","custom_code":"acme-sale"}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if !strings.Contains(w.Body.String(), "reserved") {
+		t.Errorf("body = %s, want a reserved prefix error", w.Body)
+	}
+}
+
+func TestCreateShortLinkRepoError(t *testing.T) {
+	repo := &fakeLinkRepo{err: errors.New("connection refused")}
+	w := postLink(newLinkRouter(repo, nil), `{"original_url":"https://example.com"}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(w.Body.String(), "connection refused") {
+		t.Errorf("response leaks repository error: %s", w.Body)
+	}
+}
+
+func TestBatchCreateShortLinks(t *testing.T) {
+	repo := &fakeLinkRepo{}
+	pub := &recordingPublisher{}
+	body := `[
+		{"original_url":"https://a.example"},
+		{"original_url":"ftp://b.example"},
+		{"original_url":"https://c.example","custom_code":"taken"},
+		{"custom_code":"nourl"},
+		{"original_url":"https://d.example","custom_code":"d-link"}
+	]`
+	w := postJSON(newLinkRouterWithPublisher(repo, &reqctx.User{ID: 7}, pub), "/api/v1/links/batch", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	var resp struct {
+		Data []models.BatchItemResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := []struct {
+		success bool
+		err     string
+	}{
+		{true, ""},
+		{false, "original_url must be an absolute http or https URL"},
+		{false, "custom_code is already taken"},
+		{false, "original_url is required"},
+		{true, ""},
+	}
+	if len(resp.Data) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Data), len(want))
+	}
+	for i, w := range want {
+		got := resp.Data[i]
+		if got.Index != i || got.Success != w.success || got.Error != w.err || (got.Data != nil) != w.success {
+			t.Errorf("result %d = %+v, want success=%v error=%q", i, got, w.success, w.err)
+		}
+	}
+	if resp.Data[4].Data.OriginalURL != "https://d.example" {
+		t.Errorf("result 4 link = %+v", resp.Data[4].Data)
+	}
+
+	if len(repo.gotBatch) != 3 {
+		t.Fatalf("repository got %d items, want the 3 valid ones", len(repo.gotBatch))
+	}
+	for _, in := range repo.gotBatch {
+		if in.UserID != 7 {
+			t.Errorf("item %+v not owned by the caller", in)
+		}
+	}
+	if len(pub.events) != 2 {
+		t.Errorf("published %d events, want 2", len(pub.events))
+	}
+}
+
+func TestBatchCreateShortLinksRejectsBatch(t *testing.T) {
+	tooMany := "[" + strings.TrimSuffix(strings.Repeat(`{"original_url":"https://example.com"},`, defaultMaxBatchItems+1), ",") + "]"
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"malformed", `[{`, http.StatusBadRequest},
+		{"not an array", `{"original_url":"https://example.com"}`, http.StatusBadRequest},
+		{"empty", `[]`, http.StatusBadRequest},
+		{"over the cap", tooMany, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLinkRepo{}
+			w := postJSON(newLinkRouter(repo, nil), "/api/v1/links/batch", tt.body)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if repo.called {
+				t.Error("repository was called for a rejected batch")
+			}
+		})
+	}
+}
+
+func TestBatchCreateShortLinksRepoError(t *testing.T) {
+	repo := &fakeLinkRepo{err: errors.New("connection reset")}
+	w := postJSON(newLinkRouter(repo, nil), "/api/v1/links/batch", `[{"original_url":"https://example.com"}]`)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+// uniqueCodeRepo stands in for the short_links unique constraint: the
+// first insert of a code wins and later ones get ErrCodeTaken. Every call
+// waits at arrived until all expected callers are in flight, so the
+// inserts genuinely race.
+type uniqueCodeRepo struct {
+	fakeLinkRepo
+	arrived sync.WaitGroup
+	mu      sync.Mutex
+	codes   map[string]bool
+}
+
+func (r *uniqueCodeRepo) CreateShortLink(_ context.Context, in models.NewShortLink) (*models.ShortLink, error) {
+	r.arrived.Done()
+	r.arrived.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.codes[in.CustomCode] {
+		return nil, repository.ErrCodeTaken
+	}
+	r.codes[in.CustomCode] = true
+	return &models.ShortLink{Code: in.CustomCode, OriginalURL: in.OriginalURL}, nil
+}
+
+func TestCreateShortLinkConcurrentCustomCode(t *testing.T) {
+	const callers = 2
+	repo := &uniqueCodeRepo{codes: make(map[string]bool)}
+	repo.arrived.Add(callers)
+	r := newLinkRouter(repo, nil)
+
+	statuses := make(chan int, callers)
+	for range callers {
+		go func() {
+			statuses <- postLink(r, `{"original_url":"https://example.com","custom_code":"launch"}`).Code
+		}()
+	}
+	got := map[int]int{}
+	for range callers {
+		got[<-statuses]++
+	}
+	if got[http.StatusCreated] != 1 || got[http.StatusConflict] != 1 {
+		t.Errorf("statuses = %v, want one 201 and one 409", got)
+	}
+}
+
+func TestBatchEndpointsShareConfiguredCap(t *testing.T) {
+	cfg := &config.Config{Links: config.LinkConfig{MaxBatchItems: 2}}
+	tests := []struct {
+		name, path, contentType string
+		body                    func(n int) string
+	}{
+		{"batch create", "/api/v1/links/batch", "application/json", func(n int) string {
+			return "[" + strings.TrimSuffix(strings.Repeat(`{"original_url":"https://example.com"},`, n), ",") + "]"
+		}},
+		{"import", "/api/v1/links/import?format=csv", "text/csv", func(n int) string {
+			return "long_url\n" + strings.Repeat("https://example.com\n", n)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for n, wantCode := range map[int]int{2: http.StatusOK, 3: http.StatusRequestEntityTooLarge} {
+				repo := &fakeLinkRepo{}
+				r := newLinkRouterWithHandler(NewLinkHandler(repo, events.NopPublisher{}, nil, cfg), nil)
+				req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body(n)))
+				req.Header.Set("Content-Type", tt.contentType)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				if w.Code != wantCode {
+					t.Fatalf("%d links: status = %d, want %d", n, w.Code, wantCode)
+				}
+				var resp models.Response
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if wantCode == http.StatusRequestEntityTooLarge && resp.Error != "batch must contain at most 2 links" {
+					t.Errorf("error = %q, want it to state the limit", resp.Error)
+				}
+			}
+		})
+	}
+}
+
+// batchBody returns a batch of n links in which every third has an invalid
+// URL and every fifth a custom code of its own, so results can be matched
+// back to their items.
+func batchBody(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		switch {
+		case i%3 == 0:
+			items[i] = fmt.Sprintf(`{"original_url":"ftp://bad-%d.example"}`, i)
+		case i%5 == 0:
+			items[i] = fmt.Sprintf(`{"original_url":"https://example.com/%d","custom_code":"code-%d"}`, i, i)
+		default:
+			items[i] = fmt.Sprintf(`{"original_url":"https://example.com/%d"}`, i)
+		}
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func TestBatchCreateShortLinksParallelKeepsOrder(t *testing.T) {
+	const n = 200
+	repo := &fakeLinkRepo{}
+	cfg := &config.Config{Links: config.LinkConfig{BatchWorkers: 8}}
+	w := postJSON(newLinkRouterWithHandler(NewLinkHandler(repo, events.NopPublisher{}, nil, cfg), nil),
+		"/api/v1/links/batch", batchBody(n))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp struct {
+		Data []models.BatchItemResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Data) != n {
+		t.Fatalf("got %d results, want %d", len(resp.Data), n)
+	}
+	for i, res := range resp.Data {
+		if res.Index != i {
+			t.Fatalf("result %d has index %d", i, res.Index)
+		}
+		switch {
+		case i%3 == 0:
+			if res.Success || res.Error == "" {
+				t.Errorf("item %d: %+v, want rejected", i, res)
+			}
+		case res.Data == nil || res.Data.OriginalURL != fmt.Sprintf("https://example.com/%d", i):
+			t.Errorf("item %d: %+v, want its own link", i, res)
+		}
+	}
+
+	// The repository gets the valid items in request order, in one call.
+	want := 0
+	for i, in := range repo.gotBatch {
+		for want%3 == 0 {
+			want++
+		}
+		wantCode := ""
+		if want%5 == 0 {
+			wantCode = fmt.Sprintf("code-%d", want)
+		}
+		if in.OriginalURL != fmt.Sprintf("https://example.com/%d", want) || in.CustomCode != wantCode {
+			t.Fatalf("repository item %d = %+v, want item %d", i, in, want)
+		}
+		want++
+	}
+}
+
+func BenchmarkBatchCreateShortLinks(b *testing.B) {
+	body := batchBody(1000)
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg := &config.Config{Links: config.LinkConfig{BatchWorkers: workers}}
+			r := newLinkRouterWithHandler(NewLinkHandler(&fakeLinkRepo{}, events.NopPublisher{}, nil, cfg), nil)
+			b.ResetTimer()
+			for b.Loop() {
+				if w := postJSON(r, "/api/v1/links/batch", body); w.Code != http.StatusOK {
+					b.Fatalf("status = %d", w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// Pagination defaults shared by every list endpoint.
+const (
+	defaultPage     = 1
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageParams reads the page and page_size query parameters. Missing or
+// malformed values fall back to the defaults and page_size is capped at
+// maxPageSize, so list endpoints never reject a request over paging.
+func pageParams(c *gin.Context) (page, size int) {
+	page = queryInt(c, "page", defaultPage)
+	if page < 1 {
+		page = defaultPage
+	}
+	size = queryInt(c, "page_size", defaultPageSize)
+	switch {
+	case size < 1:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+	return page, size
+}
+
+// pageOffset returns the row offset of the first item on page.
+func pageOffset(page, size int) int {
+	return (page - 1) * size
+}
+
+func queryInt(c *gin.Context, key string, def int) int {
+	v, err := strconv.Atoi(c.Query(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// totalPages is the number of pages of size needed for total items.
+func totalPages(total int64, size int) int64 {
+	if total <= 0 || size <= 0 {
+		return 0
+	}
+	return (total + int64(size) - 1) / int64(size)
+}
+
+// listResponse writes one page of items with its pagination metadata. A
+// nil items slice is sent as an empty list.
+func listResponse[T any](c *gin.Context, items []T, total int64, page, size int) {
+	if items == nil {
+		items = []T{}
+	}
+	respond.JSON(c, http.StatusOK, models.PaginatedResponse{
+		Success:    true,
+		Data:       items,
+		Page:       page,
+		PageSize:   size,
+		TotalItems: total,
+		TotalPages: totalPages(total, size),
+	})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPageParams(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantPage int
+		wantSize int
+	}{
+		{"", 1, 20},
+		{"?page=3&page_size=50", 3, 50},
+		{"?page=0&page_size=0", 1, 20},
+		{"?page=-2&page_size=-5", 1, 20},
+		{"?page=abc&page_size=xyz", 1, 20},
+		{"?page_size=100", 1, 100},
+		{"?page_size=101", 1, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+
+			page, size := pageParams(c)
+			if page != tt.wantPage || size != tt.wantSize {
+				t.Errorf("pageParams = (%d, %d), want (%d, %d)", page, size, tt.wantPage, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestPageOffset(t *testing.T) {
+	if got := pageOffset(1, 20); got != 0 {
+		t.Errorf("pageOffset(1, 20) = %d, want 0", got)
+	}
+	if got := pageOffset(3, 20); got != 40 {
+		t.Errorf("pageOffset(3, 20) = %d, want 40", got)
+	}
+}
+
+func TestTotalPages(t *testing.T) {
+	tests := []struct {
+		total int64
+		size  int
+		want  int64
+	}{
+		{0, 20, 0},
+		{1, 20, 1},
+		{20, 20, 1},
+		{21, 20, 2},
+		{100, 100, 1},
+		{101, 100, 2},
+	}
+	for _, tt := range tests {
+		if got := totalPages(tt.total, tt.size); got != tt.want {
+			t.Errorf("totalPages(%d, %d) = %d, want %d", tt.total, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestListResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	listResponse(c, []string{"a", "b"}, 45, 2, 20)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var got struct {
+		Success    bool     `json:"success"`
+		Data       []string `json:"data"`
+		Page       int      `json:"page"`
+		PageSize   int      `json:"page_size"`
+		TotalItems int64    `json:"total_items"`
+		TotalPages int64    `json:"total_pages"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !got.Success || len(got.Data) != 2 || got.Page != 2 || got.PageSize != 20 || got.TotalItems != 45 || got.TotalPages != 3 {
+		t.Errorf("response = %+v", got)
+	}
+}
+
+func TestListResponseEmpty(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	listResponse[int](c, nil, 0, 1, 20)
+
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if string(got["data"]) != "[]" {
+		t.Errorf("data = %s, want []", got["data"])
+	}
+}
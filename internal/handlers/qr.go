@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// QR code image sizes in pixels.
+const (
+	defaultQRSize = 256
+	maxQRSize     = 1024
+)
+
+// LinkGetter looks up a link by its code. *repository.PostgresRepo
+// implements it.
+type LinkGetter interface {
+	GetShortLinkByCode(ctx context.Context, code string) (*models.ShortLink, error)
+}
+
+// QRHandler serves QR codes for short links.
+type QRHandler struct {
+	repo    LinkGetter
+	baseURL string
+	maxAge  time.Duration
+}
+
+// NewQRHandler returns a QRHandler whose codes encode baseURL + "/" + code.
+// An empty baseURL is derived from each request. Images may be cached for
+// maxAge.
+func NewQRHandler(repo LinkGetter, baseURL string, maxAge time.Duration) *QRHandler {
+	return &QRHandler{repo: repo, baseURL: baseURL, maxAge: maxAge}
+}
+
+// QRCode handles GET /api/v1/links/:code/qr. It responds with a PNG QR code
+// of the link's full short URL, size pixels square. Like page_size, a
+// missing or malformed size falls back to the default and is capped at
+// maxQRSize.
+//
+// The image is cacheable for the handler's max age, or until the link
+// expires if that is sooner. Its ETag changes with the link's destination,
+// and a matching If-None-Match is answered with 304 before the image is
+// drawn.
+func (h *QRHandler) QRCode(c *gin.Context) {
+	size := queryInt(c, "size", defaultQRSize)
+	switch {
+	case size < 1:
+		size = defaultQRSize
+	case size > maxQRSize:
+		size = maxQRSize
+	}
+
+	link, err := h.repo.GetShortLinkByCode(c.Request.Context(), c.Param("code"))
+	if errors.Is(err, repository.ErrLinkNotFound) {
+		respond.JSON(c, http.StatusNotFound, models.Response{Error: "short link not found"})
+		return
+	}
+	if err != nil {
+		storageFailure(c, err, "failed to get short link")
+		return
+	}
+
+	content := shortURL(c.Request, h.baseURL, link.Code)
+	etag := qrETag(link, content, size)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", h.cacheControl(link, time.Now()))
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	png, err := qrcode.Encode(content, qrcode.Medium, size)
+	if err != nil {
+		c.Header("ETag", "")
+		c.Header("Cache-Control", "")
+		_ = c.Error(err)
+		respond.JSON(c, http.StatusInternalServerError, models.Response{Error: "failed to generate QR code"})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// qrETag returns a strong ETag for the QR image of link encoding content at
+// size. The destination is part of it so that caches drop the image when
+// the link is changed to point elsewhere.
+func qrETag(link *models.ShortLink, content string, size int) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{link.Code, link.OriginalURL, content, strconv.Itoa(size)}, "\x00")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// cacheControl returns the Cache-Control value for the QR image of link,
+// capping max-age at the time the link has left.
+func (h *QRHandler) cacheControl(link *models.ShortLink, now time.Time) string {
+	maxAge := h.maxAge
+	if link.ExpiresAt != nil {
+		maxAge = min(maxAge, max(link.ExpiresAt.Sub(now), 0))
+	}
+	if maxAge < time.Second {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int64(maxAge/time.Second))
+}
+
+// etagMatches reports whether the If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 prescribes for it.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
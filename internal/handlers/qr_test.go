@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+)
+
+type fakeGetter map[string]*models.ShortLink
+
+func (f fakeGetter) GetShortLinkByCode(_ context.Context, code string) (*models.ShortLink, error) {
+	switch code {
+	case "broken":
+		return nil, errors.New("syntax error")
+	case "down":
+		return nil, fmt.Errorf("failed to get short link: %w", repository.ErrUnavailable)
+	}
+	link, ok := f[code]
+	if !ok {
+		return nil, repository.ErrLinkNotFound
+	}
+	return link, nil
+}
+
+func getQR(baseURL, target string) *httptest.ResponseRecorder {
+	repo := fakeGetter{"abc": {Code: "abc", OriginalURL: "https://example.com"}}
+	return serveQR(NewQRHandler(repo, baseURL, time.Hour), target, "")
+}
+
+func serveQR(h *QRHandler, target, ifNoneMatch string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/links/:code/qr", h.QRCode)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestQRCodeSize(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", defaultQRSize},
+		{"?size=512", 512},
+		{"?size=5000", maxQRSize},
+		{"?size=0", defaultQRSize},
+		{"?size=big", defaultQRSize},
+	}
+	for _, tt := range tests {
+		w := getQR("https://sho.rt/", "/links/abc/qr"+tt.query)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("%q: status = %d, content type %q", tt.query, w.Code, w.Header().Get("Content-Type"))
+		}
+		img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("%q: decode PNG: %v", tt.query, err)
+		}
+		if b := img.Bounds(); b.Dx() != tt.want || b.Dy() != tt.want {
+			t.Errorf("%q: image is %dx%d, want %dx%d", tt.query, b.Dx(), b.Dy(), tt.want, tt.want)
+		}
+	}
+}
+
+func TestQRCodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want int
+	}{
+		{"unknown code", "nope", http.StatusNotFound},
+		{"repository error", "broken", http.StatusInternalServerError},
+		{"database unavailable", "down", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getQR("https://sho.rt", "/links/"+tt.code+"/qr")
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			wantRetry := ""
+			if tt.want == http.StatusServiceUnavailable {
+				wantRetry = "5"
+			}
+			if got := w.Header().Get("Retry-After"); got != wantRetry {
+				t.Errorf("Retry-After = %q, want %q", got, wantRetry)
+			}
+		})
+	}
+}
+
+func TestQRCodeCaching(t *testing.T) {
+	link := &models.ShortLink{Code: "abc", OriginalURL: "https://example.com"}
+	h := NewQRHandler(fakeGetter{"abc": link}, "https://sho.rt", 24*time.Hour)
+
+	w := serveQR(h, "/links/abc/qr", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=86400" {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	etag := w.Header().Get("ETag")
+	if len(etag) < 3 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		t.Fatalf("ETag = %q, want a quoted strong tag", etag)
+	}
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := serveQR(h, "/links/abc/qr", inm)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status = %d with %d bytes, want an empty 304", inm, w.Code, w.Body.Len())
+		}
+		if w.Header().Get("ETag") != etag || w.Header().Get("Cache-Control") == "" {
+			t.Errorf("If-None-Match %s: 304 lacks the caching headers: %v", inm, w.Header())
+		}
+	}
+
+	if got := serveQR(h, "/links/abc/qr?size=512", "").Header().Get("ETag"); got == etag {
+		t.Error("ETag does not change with the size")
+	}
+	link.OriginalURL = "https://example.org"
+	w = serveQR(h, "/links/abc/qr", etag)
+	if w.Code != http.StatusOK {
+		t.Errorf("after changing the destination: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("ETag"); got == etag {
+		t.Error("ETag does not change with the destination")
+	}
+}
+
+func TestQRCodeCacheControl(t *testing.T) {
+	now := time.Now()
+	soon, past := now.Add(90*time.Second), now.Add(-time.Minute)
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		expires *time.Time
+		want    string
+	}{
+		{"no expiry", time.Hour, nil, "public, max-age=3600"},
+		{"expires sooner", time.Hour, &soon, "public, max-age=90"},
+		{"expired", time.Hour, &past, "no-cache"},
+		{"caching disabled", 0, nil, "no-cache"},
+	}
+	for _, tt := range tests {
+		h := NewQRHandler(nil, "", tt.maxAge)
+		got := h.cacheControl(&models.ShortLink{ExpiresAt: tt.expires}, now)
+		if got != tt.want {
+			t.Errorf("%s: Cache-Control = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
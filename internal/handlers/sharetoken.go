@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reqctx"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// ShareTokenRepository mints and revokes share tokens.
+// *repository.PostgresRepo implements it.
+type ShareTokenRepository interface {
+	CreateShareToken(ctx context.Context, userID int64, scope string) (*models.ShareToken, error)
+	RevokeShareToken(ctx context.Context, userID, id int64) error
+}
+
+// ShareTokenHandler serves the share token endpoints.
+type ShareTokenHandler struct {
+	repo ShareTokenRepository
+}
+
+// NewShareTokenHandler returns a ShareTokenHandler backed by repo.
+func NewShareTokenHandler(repo ShareTokenRepository) *ShareTokenHandler {
+	return &ShareTokenHandler{repo: repo}
+}
+
+// CreateShareToken handles POST /api/v1/me/share-tokens. It mints a token
+// of the requested scope for the caller and responds 201 with it. The
+// token is shown only in this response.
+func (h *ShareTokenHandler) CreateShareToken(c *gin.Context) {
+	user, ok := reqctx.UserFromContext(c.Request.Context())
+	if !ok {
+		respond.JSON(c, http.StatusUnauthorized, models.Response{Error: "authentication required"})
+		return
+	}
+	var req models.CreateShareTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: bindErrorMessage(err)})
+		return
+	}
+
+	st, err := h.repo.CreateShareToken(c.Request.Context(), user.ID, req.Scope)
+	if err != nil {
+		storageFailure(c, err, "failed to create share token")
+		return
+	}
+	respond.JSON(c, http.StatusCreated, models.Response{Success: true, Data: st})
+}
+
+// RevokeShareToken handles DELETE /api/v1/me/share-tokens/:id. It responds
+// 204 once the caller's token is revoked, or 404 if the caller has no such
+// active token.
+func (h *ShareTokenHandler) RevokeShareToken(c *gin.Context) {
+	user, ok := reqctx.UserFromContext(c.Request.Context())
+	if !ok {
+		respond.JSON(c, http.StatusUnauthorized, models.Response{Error: "authentication required"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.JSON(c, http.StatusBadRequest, models.Response{Error: "id must be a positive integer"})
+		return
+	}
+
+	err = h.repo.RevokeShareToken(c.Request.Context(), user.ID, id)
+	if errors.Is(err, repository.ErrShareTokenNotFound) {
+		respond.JSON(c, http.StatusNotFound, models.Response{Error: "share token not found"})
+		return
+	}
+	if err != nil {
+		storageFailure(c, err, "failed to revoke share token")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
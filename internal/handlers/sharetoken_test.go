@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+// fakeShareTokens keeps tokens by ID; revoked ones are deleted.
+type fakeShareTokens struct {
+	nextID int64
+	tokens map[int64]models.ShareToken
+}
+
+func (f *fakeShareTokens) CreateShareToken(_ context.Context, userID int64, scope string) (*models.ShareToken, error) {
+	f.nextID++
+	st := models.ShareToken{ID: f.nextID, UserID: userID, Scope: scope, Token: "st_secret"}
+	f.tokens[st.ID] = st
+	return &st, nil
+}
+
+func (f *fakeShareTokens) RevokeShareToken(_ context.Context, userID, id int64) error {
+	st, ok := f.tokens[id]
+	if !ok || st.UserID != userID {
+		return repository.ErrShareTokenNotFound
+	}
+	delete(f.tokens, id)
+	return nil
+}
+
+func newShareTokenRouter(repo ShareTokenRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewShareTokenHandler(repo)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Test-User"); id != "" {
+			user := reqctx.User{ID: 7}
+			if id == "other" {
+				user.ID = 8
+			}
+			c.Request = c.Request.WithContext(reqctx.WithUser(c.Request.Context(), user))
+		}
+	})
+	r.POST("/api/v1/me/share-tokens", h.CreateShareToken)
+	r.DELETE("/api/v1/me/share-tokens/:id", h.RevokeShareToken)
+	return r
+}
+
+func serveAs(r http.Handler, user, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestShareTokenMintAndRevoke(t *testing.T) {
+	repo := &fakeShareTokens{tokens: map[int64]models.ShareToken{}}
+	r := newShareTokenRouter(repo)
+
+	w := serveAs(r, "me", http.MethodPost, "/api/v1/me/share-tokens", `{"scope":"feed"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("mint: status = %d, want %d; body %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var resp struct {
+		Data models.ShareToken `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.ID != 1 || resp.Data.UserID != 7 || resp.Data.Scope != models.ShareScopeFeed || resp.Data.Token != "st_secret" {
+		t.Errorf("minted %+v", resp.Data)
+	}
+
+	steps := []struct {
+		name, user string
+		wantCode   int
+	}{
+		{"other user", "other", http.StatusNotFound},
+		{"owner", "me", http.StatusNoContent},
+		{"already revoked", "me", http.StatusNotFound},
+	}
+	for _, s := range steps {
+		if w := serveAs(r, s.user, http.MethodDelete, "/api/v1/me/share-tokens/1", ""); w.Code != s.wantCode {
+			t.Errorf("revoke as %s: status = %d, want %d", s.name, w.Code, s.wantCode)
+		}
+	}
+}
+
+func TestShareTokenRejectsBadRequests(t *testing.T) {
+	r := newShareTokenRouter(&fakeShareTokens{tokens: map[int64]models.ShareToken{}})
+	tests := []struct {
+		name, user, method, path, body string
+		wantCode                       int
+	}{
+		{"mint anonymously", "", http.MethodPost, "/api/v1/me/share-tokens", `{"scope":"feed"}`, http.StatusUnauthorized},
+		{"missing scope", "me", http.MethodPost, "/api/v1/me/share-tokens", `{}`, http.StatusBadRequest},
+		{"unknown scope", "me", http.MethodPost, "/api/v1/me/share-tokens", `{"scope":"admin"}`, http.StatusBadRequest},
+		{"revoke anonymously", "", http.MethodDelete, "/api/v1/me/share-tokens/1", "", http.StatusUnauthorized},
+		{"bad id", "me", http.MethodDelete, "/api/v1/me/share-tokens/abc", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := serveAs(r, tt.user, tt.method, tt.path, tt.body); w.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantCode)
+		}
+	}
+}
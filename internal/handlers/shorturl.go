@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// shortURL returns the public URL of code, base + "/" + code. Without a
+// configured base it is derived from the request: its Host, over https if
+// the request arrived over TLS or through a proxy reporting
+// X-Forwarded-Proto: https.
+func shortURL(r *http.Request, base, code string) string {
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return strings.TrimRight(base, "/") + "/" + code
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestShortURL(t *testing.T) {
+	plain := httptest.NewRequest(http.MethodGet, "http://api.internal:8080/x", nil)
+	secure := httptest.NewRequest(http.MethodGet, "https://sho.rt/x", nil)
+	secure.TLS = &tls.ConnectionState{}
+	proxied := httptest.NewRequest(http.MethodGet, "http://sho.rt/x", nil)
+	proxied.Header.Set("X-Forwarded-Proto", "https")
+
+	tests := []struct {
+		name string
+		r    *http.Request
+		base string
+		want string
+	}{
+		{"configured", plain, "https://sho.rt/", "https://sho.rt/abc"},
+		{"configured with path", plain, "https://example.com/s", "https://example.com/s/abc"},
+		{"derived", plain, "", "http://api.internal:8080/abc"},
+		{"derived over TLS", secure, "", "https://sho.rt/abc"},
+		{"derived behind proxy", proxied, "", "https://sho.rt/abc"},
+	}
+	for _, tt := range tests {
+		if got := shortURL(tt.r, tt.base, "abc"); got != tt.want {
+			t.Errorf("%s: shortURL = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/respond"
+	"github.com/maojcn/shortlink/internal/retryafter"
+)
+
+// unavailableRetryAfter is how long clients are told to wait after a 503
+// caused by losing the database connection.
+const unavailableRetryAfter = 5 * time.Second
+
+// storageFailure records err on c and responds to it: with 503 and
+// Retry-After if the database connection was lost, since a retry may
+// succeed, or with 500 and message otherwise.
+func storageFailure(c *gin.Context, err error, message string) {
+	_ = c.Error(err)
+	if errors.Is(err, repository.ErrUnavailable) {
+		now := time.Now()
+		retryafter.Set(c.Writer.Header(), now.Add(unavailableRetryAfter), now, retryafter.Seconds)
+		respond.JSON(c, http.StatusServiceUnavailable, models.Response{Error: "database temporarily unavailable"})
+		return
+	}
+	respond.JSON(c, http.StatusInternalServerError, models.Response{Error: message})
+}
@@ -0,0 +1,168 @@
+// Package importer reads link exports from other URL shorteners so they can
+// be recreated here.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Supported export sources and formats.
+const (
+	SourceBitly = "bitly"
+
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// ErrUnsupported is returned by Parse for an unknown source or format.
+var ErrUnsupported = errors.New("unsupported import source or format")
+
+// Record is one link of an export. ShortCode is empty when the export does
+// not name one, and Created is nil when it has no usable timestamp.
+type Record struct {
+	LongURL   string
+	ShortCode string
+	Created   *time.Time
+}
+
+// Parse reads the records of an export from source ("bitly") in format
+// ("csv" or "json").
+//
+// Bitly exports carry long_url, short_code and created columns or fields.
+// When short_code is missing, the code is taken from the last path segment
+// of link (e.g. "https://bit.ly/3xYz" gives "3xYz"), and created_at is
+// accepted in place of created. Timestamps that cannot be parsed are
+// dropped rather than failing the import.
+func Parse(source, format string, r io.Reader) ([]Record, error) {
+	if source != SourceBitly {
+		return nil, fmt.Errorf("%w: source %q", ErrUnsupported, source)
+	}
+	switch format {
+	case FormatCSV:
+		return parseBitlyCSV(r)
+	case FormatJSON:
+		return parseBitlyJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: format %q", ErrUnsupported, format)
+	}
+}
+
+// bitlyLink is one link of a Bitly export, in either format.
+type bitlyLink struct {
+	LongURL   string `json:"long_url"`
+	ShortCode string `json:"short_code"`
+	Link      string `json:"link"`
+	Created   string `json:"created"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (l bitlyLink) record() Record {
+	rec := Record{LongURL: strings.TrimSpace(l.LongURL), ShortCode: strings.TrimSpace(l.ShortCode)}
+	if rec.ShortCode == "" && l.Link != "" {
+		if u, err := url.Parse(strings.TrimSpace(l.Link)); err == nil && u.Path != "" {
+			rec.ShortCode = path.Base(u.Path)
+		}
+	}
+	created := l.Created
+	if created == "" {
+		created = l.CreatedAt
+	}
+	rec.Created = parseTime(created)
+	return rec
+}
+
+func parseBitlyCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := col["long_url"]; !ok {
+		return nil, errors.New("CSV header has no long_url column")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var records []Record
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		records = append(records, bitlyLink{
+			LongURL:   field(row, "long_url"),
+			ShortCode: field(row, "short_code"),
+			Link:      field(row, "link"),
+			Created:   field(row, "created"),
+			CreatedAt: field(row, "created_at"),
+		}.record())
+	}
+}
+
+// parseBitlyJSON accepts either an array of links or an object with the
+// links under "links", as the Bitly API returns them.
+func parseBitlyJSON(r io.Reader) ([]Record, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+	var links []bitlyLink
+	if err := json.Unmarshal(raw, &links); err != nil {
+		var wrapped struct {
+			Links []bitlyLink `json:"links"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON: %w", err)
+		}
+		links = wrapped.Links
+	}
+	records := make([]Record, len(links))
+	for i, l := range links {
+		records[i] = l.record()
+	}
+	return records, nil
+}
+
+// timeLayouts are the timestamp forms seen in Bitly exports.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseTime returns the time s denotes, or nil if it matches no layout.
+// Times without a zone are taken as UTC.
+func parseTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
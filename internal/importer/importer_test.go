@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseFile(t *testing.T, format, name string) []Record {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := Parse(SourceBitly, format, f)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return records
+}
+
+func timeString(t *time.Time) string {
+	if t == nil {
+		return "<nil>"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func TestParseBitlyCSV(t *testing.T) {
+	records := parseFile(t, FormatCSV, "bitly.csv")
+	want := []struct{ url, code, created string }{
+		{"https://example.com/launch", "launch19", "2019-03-01T09:30:00Z"},
+		{"https://example.org/docs?page=2", "", "2020-07-15T12:00:00Z"},
+		{"https://example.net/", "3xYzAbc", "<nil>"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.LongURL != w.url || r.ShortCode != w.code || timeString(r.Created) != w.created {
+			t.Errorf("record %d = {%q %q %s}, want %+v", i, r.LongURL, r.ShortCode, timeString(r.Created), w)
+		}
+	}
+}
+
+func TestParseBitlyJSON(t *testing.T) {
+	records := parseFile(t, FormatJSON, "bitly.json")
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if r := records[0]; r.ShortCode != "launch19" || timeString(r.Created) != "2019-03-01T09:30:00Z" {
+		t.Errorf("record 0 = %+v, want code from link and created_at", r)
+	}
+	if r := records[1]; r.ShortCode != "docs" || timeString(r.Created) != "2020-07-15T12:00:00Z" {
+		t.Errorf("record 1 = %+v", r)
+	}
+}
+
+func TestParseBitlyJSONArray(t *testing.T) {
+	records, err := Parse(SourceBitly, FormatJSON, strings.NewReader(`[{"long_url":"https://example.com","short_code":"abc"}]`))
+	if err != nil || len(records) != 1 || records[0].ShortCode != "abc" || records[0].Created != nil {
+		t.Errorf("Parse = %+v, %v", records, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, source, format, body string
+		unsupported                bool
+	}{
+		{"unknown source", "tinyurl", FormatCSV, "long_url\n", true},
+		{"unknown format", SourceBitly, "xml", "", true},
+		{"no long_url column", SourceBitly, FormatCSV, "url,code\nhttps://example.com,abc\n", false},
+		{"empty CSV", SourceBitly, FormatCSV, "", false},
+		{"bad JSON", SourceBitly, FormatJSON, "{", false},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.source, tt.format, strings.NewReader(tt.body))
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+			continue
+		}
+		if errors.Is(err, ErrUnsupported) != tt.unsupported {
+			t.Errorf("%s: error = %v, unsupported = %v", tt.name, err, tt.unsupported)
+		}
+	}
+}
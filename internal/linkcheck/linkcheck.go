@@ -0,0 +1,141 @@
+// Package linkcheck checks whether link destinations are still reachable.
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+// Status is the outcome of checking one destination.
+type Status string
+
+// Check outcomes. Broken covers both error responses and destinations that
+// could not be reached at all.
+const (
+	StatusOK      Status = "ok"
+	StatusBroken  Status = "broken"
+	StatusTimeout Status = "timeout"
+)
+
+// Result is the outcome for one URL. HTTPStatus is 0 when no response was
+// received.
+type Result struct {
+	URL        string `json:"url"`
+	Status     Status `json:"status"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+}
+
+// Checker sends HEAD requests to destinations with bounded concurrency and
+// caches the results briefly.
+type Checker struct {
+	client      *http.Client
+	concurrency int
+	timeout     time.Duration
+	ttl         time.Duration
+	now         func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedResult
+}
+
+type cachedResult struct {
+	result  Result
+	expires time.Time
+}
+
+// New returns a Checker that sends its requests with client, which should
+// refuse internal addresses (see outbound.NewClient).
+func New(client *http.Client, cfg config.LinkCheckConfig) *Checker {
+	return &Checker{
+		client:      client,
+		concurrency: max(cfg.Concurrency, 1),
+		timeout:     cfg.Timeout,
+		ttl:         cfg.CacheTTL,
+		now:         time.Now,
+		cache:       make(map[string]cachedResult),
+	}
+}
+
+// Check returns one Result per URL, in the same order.
+func (c *Checker) Check(ctx context.Context, urls []string) []Result {
+	results := make([]Result, len(urls))
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		if r, ok := c.cached(u); ok {
+			results[i] = r
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.check(ctx, u)
+			c.store(results[i])
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (c *Checker) check(ctx context.Context, url string) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	result := Result{URL: url, Status: StatusBroken}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return result
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			result.Status = StatusTimeout
+		}
+		return result
+	}
+	resp.Body.Close()
+
+	result.HTTPStatus = resp.StatusCode
+	if resp.StatusCode < http.StatusBadRequest {
+		result.Status = StatusOK
+	}
+	return result
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (c *Checker) cached(url string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[url]
+	if !ok || !c.now().Before(entry.expires) {
+		return Result{}, false
+	}
+	return entry.result, true
+}
+
+func (c *Checker) store(r Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for url, entry := range c.cache {
+		if !now.Before(entry.expires) {
+			delete(c.cache, url)
+		}
+	}
+	c.cache[r.URL] = cachedResult{result: r, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,109 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+func newTestChecker(client *http.Client, concurrency int) *Checker {
+	return New(client, config.LinkCheckConfig{
+		Concurrency: concurrency,
+		Timeout:     100 * time.Millisecond,
+		CacheTTL:    time.Minute,
+	})
+}
+
+func TestCheck(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %s, want HEAD", r.Method)
+		}
+		switch r.URL.Path {
+		case "/ok":
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case "/slow":
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	urls := []string{srv.URL + "/ok", srv.URL + "/gone", srv.URL + "/slow", "http://127.0.0.1:1/refused"}
+	got := newTestChecker(srv.Client(), 4).Check(context.Background(), urls)
+
+	want := []Result{
+		{URL: urls[0], Status: StatusOK, HTTPStatus: http.StatusOK},
+		{URL: urls[1], Status: StatusBroken, HTTPStatus: http.StatusNotFound},
+		{URL: urls[2], Status: StatusTimeout},
+		{URL: urls[3], Status: StatusBroken},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCheckBoundsConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	var urls []string
+	for i := 0; i < 8; i++ {
+		urls = append(urls, srv.URL+"/"+string(rune('a'+i)))
+	}
+	newTestChecker(srv.Client(), 2).Check(context.Background(), urls)
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d requests in flight, want at most 2", p)
+	}
+}
+
+func TestCheckCachesResults(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	c := newTestChecker(srv.Client(), 1)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	c.Check(context.Background(), []string{srv.URL})
+	c.Check(context.Background(), []string{srv.URL})
+	if n := hits.Load(); n != 1 {
+		t.Errorf("destination hit %d times within the TTL, want 1", n)
+	}
+
+	now = now.Add(time.Minute)
+	c.Check(context.Background(), []string{srv.URL})
+	if n := hits.Load(); n != 2 {
+		t.Errorf("destination hit %d times after the TTL, want 2", n)
+	}
+}
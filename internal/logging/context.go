@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger for FromContext.
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored by WithLogger, or a no-op logger,
+// with request_id and user_id fields for the request ID and user in ctx.
+// The fields are read at call time, so a user authenticated after the
+// logger was stored is included.
+func FromContext(ctx context.Context) *zap.Logger {
+	logger, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	if !ok {
+		logger = zap.NewNop()
+	}
+	var fields []zap.Field
+	if id := reqctx.RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if u, ok := reqctx.UserFromContext(ctx); ok {
+		fields = append(fields, zap.Int64("user_id", u.ID))
+	}
+	return logger.With(fields...)
+}
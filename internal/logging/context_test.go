@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+func TestFromContextAddsRequestFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := WithLogger(context.Background(), zap.New(core))
+	ctx = reqctx.WithRequestID(ctx, "req-1")
+	ctx = reqctx.WithUser(ctx, reqctx.User{ID: 7})
+
+	FromContext(ctx).Info("link created")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["request_id"] != "req-1" || fields["user_id"] != int64(7) {
+		t.Errorf("fields = %v, want request_id and user_id", fields)
+	}
+}
+
+func TestFromContextWithoutRequest(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	FromContext(WithLogger(context.Background(), zap.New(core))).Info("startup")
+	if fields := logs.All()[0].ContextMap(); len(fields) != 0 {
+		t.Errorf("fields = %v, want none", fields)
+	}
+
+	// Without a stored logger FromContext must still be usable.
+	FromContext(context.Background()).Info("dropped")
+}
@@ -0,0 +1,54 @@
+// Package logging builds the service's zap logger.
+package logging
+
+import (
+	"fmt"
+	"os"
+
+	zaplogfmt "github.com/jsternberg/zap-logfmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+// Supported values of config.LogConfig.Format.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+	FormatLogfmt  = "logfmt"
+)
+
+// New returns a logger writing to stderr at cfg.Level in cfg.Format.
+func New(cfg config.LogConfig) (*zap.Logger, error) {
+	return newLogger(cfg, zapcore.Lock(os.Stderr))
+}
+
+func newLogger(cfg config.LogConfig, out zapcore.WriteSyncer) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	}
+	enc, err := newEncoder(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+	return zap.New(zapcore.NewCore(enc, out, level), zap.AddCaller()), nil
+}
+
+func newEncoder(format string) (zapcore.Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), nil
+	case FormatConsole:
+		ec := zap.NewDevelopmentEncoderConfig()
+		ec.EncodeLevel = zapcore.CapitalLevelEncoder
+		return zapcore.NewConsoleEncoder(ec), nil
+	case FormatLogfmt:
+		ec := zap.NewProductionEncoderConfig()
+		ec.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zaplogfmt.NewEncoder(ec), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: want %s, %s or %s", format, FormatJSON, FormatConsole, FormatLogfmt)
+	}
+}
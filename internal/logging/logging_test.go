@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+func logSample(t *testing.T, format string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := newLogger(config.LogConfig{Level: "info", Format: format}, zapcore.AddSync(&buf))
+	if err != nil {
+		t.Fatalf("newLogger(%q): %v", format, err)
+	}
+	logger.Info("link created", zap.String("code", "10"), zap.Int("status", 201))
+	return strings.TrimSpace(buf.String())
+}
+
+func TestJSONFormat(t *testing.T) {
+	line := logSample(t, FormatJSON)
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("not JSON: %q", line)
+	}
+	if entry["level"] != "info" || entry["msg"] != "link created" || entry["code"] != "10" || entry["status"] != float64(201) {
+		t.Errorf("entry = %v", entry)
+	}
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	line := logSample(t, FormatLogfmt)
+	want := regexp.MustCompile(`^ts=\S+ level=info caller=\S+ msg="link created" code=10 status=201$`)
+	if !want.MatchString(line) {
+		t.Errorf("line = %q, want match for %s", line, want)
+	}
+}
+
+func TestConsoleFormat(t *testing.T) {
+	line := logSample(t, FormatConsole)
+	want := regexp.MustCompile(`^\S+\tINFO\t\S+\tlink created\t\{"code": "10", "status": 201\}$`)
+	if !want.MatchString(line) {
+		t.Errorf("line = %q, want match for %s", line, want)
+	}
+}
+
+func TestLevelFilters(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(config.LogConfig{Level: "warn", Format: FormatJSON}, zapcore.AddSync(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("dropped")
+	if buf.Len() != 0 {
+		t.Errorf("info entry written at warn level: %q", buf.String())
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	if _, err := New(config.LogConfig{Level: "info", Format: "xml"}); err == nil {
+		t.Error("accepted format xml")
+	}
+	if _, err := New(config.LogConfig{Level: "loud", Format: FormatJSON}); err == nil {
+		t.Error("accepted level loud")
+	}
+}
@@ -0,0 +1,59 @@
+// Package middleware contains Gin middleware shared by the routes.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reqctx"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// APIKeyRepository resolves API keys. *repository.PostgresRepo implements
+// it.
+type APIKeyRepository interface {
+	UserIDForAPIKey(ctx context.Context, key string) (int64, error)
+}
+
+// APIKeyAuth rejects requests without a valid API key with 401. The key is
+// read from "Authorization: Bearer <key>" or, failing that, X-API-Key. The
+// owning user is attached to the request context with reqctx.WithUser.
+func APIKeyAuth(repo APIKeyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := apiKeyFromRequest(c.Request)
+		if key == "" {
+			respond.AbortJSON(c, http.StatusUnauthorized, models.Response{Error: "missing API key"})
+			return
+		}
+
+		userID, err := repo.UserIDForAPIKey(c.Request.Context(), key)
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			respond.AbortJSON(c, http.StatusUnauthorized, models.Response{Error: "invalid API key"})
+			return
+		}
+		if err != nil {
+			storageFailure(c, err, "failed to verify API key")
+			return
+		}
+
+		ctx := reqctx.WithUser(c.Request.Context(), reqctx.User{ID: userID})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, key, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(key)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
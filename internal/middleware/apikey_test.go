@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+type fakeKeys map[string]int64
+
+func (f fakeKeys) UserIDForAPIKey(_ context.Context, key string) (int64, error) {
+	switch key {
+	case "broken":
+		return 0, errors.New("syntax error")
+	case "down":
+		return 0, fmt.Errorf("failed to look up api key: %w", repository.ErrUnavailable)
+	}
+	id, ok := f[key]
+	if !ok {
+		return 0, repository.ErrAPIKeyNotFound
+	}
+	return id, nil
+}
+
+func newAuthRouter(gotUser *reqctx.User) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(APIKeyAuth(fakeKeys{"good-key": 42}))
+	r.GET("/", func(c *gin.Context) {
+		*gotUser, _ = reqctx.UserFromContext(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		wantCode int
+	}{
+		{"bearer", "Authorization", "Bearer good-key", http.StatusNoContent},
+		{"bearer lowercase", "Authorization", "bearer good-key", http.StatusNoContent},
+		{"x-api-key", "X-API-Key", "good-key", http.StatusNoContent},
+		{"missing", "", "", http.StatusUnauthorized},
+		{"unknown key", "X-API-Key", "other", http.StatusUnauthorized},
+		{"basic auth", "Authorization", "Basic good-key", http.StatusUnauthorized},
+		{"repository error", "X-API-Key", "broken", http.StatusInternalServerError},
+		{"database unavailable", "X-API-Key", "down", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user reqctx.User
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			newAuthRouter(&user).ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusNoContent && user.ID != 42 {
+				t.Errorf("user = %+v, want ID 42", user)
+			}
+			if tt.wantCode == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "5" {
+				t.Errorf("Retry-After = %q, want 5", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
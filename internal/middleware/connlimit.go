@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// PerIPConcurrency rejects with 429 a request from a client IP that already
+// has limit requests in flight, so that one client holding many slow
+// requests open cannot tie up the server. The IP is c.ClientIP, which only
+// honors forwarding headers from the engine's trusted proxies. Clients in
+// the exempt networks, given as CIDRs or single IPs, are never limited;
+// entries that do not parse are skipped, as config.Load has already
+// rejected them. A limit of zero or less disables the limit.
+func PerIPConcurrency(limit int, exempt []string) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	nets := parseNetworks(exempt)
+	var (
+		mu       sync.Mutex
+		inFlight = make(map[string]int)
+	)
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if isExempt(nets, ip) {
+			c.Next()
+			return
+		}
+
+		mu.Lock()
+		if inFlight[ip] >= limit {
+			mu.Unlock()
+			// There is no reset time to report; a request may finish at
+			// any moment, so the client is asked to wait the minimum.
+			c.Header("Retry-After", "1")
+			respond.AbortJSON(c, http.StatusTooManyRequests, models.Response{Error: "too many concurrent requests"})
+			return
+		}
+		inFlight[ip]++
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if inFlight[ip]--; inFlight[ip] == 0 {
+				delete(inFlight, ip)
+			}
+		}()
+		c.Next()
+	}
+}
+
+// parseNetworks parses CIDRs and single IPs, skipping invalid entries.
+func parseNetworks(list []string) []netip.Prefix {
+	var nets []netip.Prefix
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if p, err := netip.ParsePrefix(s); err == nil {
+			nets = append(nets, p.Masked())
+		} else if a, err := netip.ParseAddr(s); err == nil {
+			nets = append(nets, netip.PrefixFrom(a, a.BitLen()))
+		}
+	}
+	return nets
+}
+
+func isExempt(nets []netip.Prefix, ip string) bool {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	a = a.Unmap()
+	for _, n := range nets {
+		if n.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
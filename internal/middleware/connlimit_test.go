@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPerIPConcurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r.Use(PerIPConcurrency(2, []string{"10.9.0.0/16", "192.0.2.7"}))
+	r.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	get := func(path, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Hold the limit of slow requests open, from one client and from an
+	// exempt one.
+	var wg sync.WaitGroup
+	codes := make(chan int, 5)
+	for _, addr := range []string{"203.0.113.1:1000", "203.0.113.1:1001", "10.9.3.4:1000", "10.9.3.4:1001", "10.9.3.4:1002"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- get("/slow", addr, "")
+		}()
+		<-started
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		wantCode     int
+	}{
+		{"over the limit", "203.0.113.1:2000", "", http.StatusTooManyRequests},
+		{"untrusted forwarding header is ignored", "203.0.113.1:2001", "198.51.100.1", http.StatusTooManyRequests},
+		{"another client", "203.0.113.2:1000", "", http.StatusNoContent},
+		{"exempt network", "10.9.3.4:2000", "", http.StatusNoContent},
+		{"exempt address", "192.0.2.7:2000", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if got := get("/", tt.remoteAddr, tt.forwardedFor); got != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.wantCode)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusNoContent {
+			t.Errorf("held request status = %d, want %d", code, http.StatusNoContent)
+		}
+	}
+	if got := get("/", "203.0.113.1:3000", ""); got != http.StatusNoContent {
+		t.Errorf("after the held requests finished: status = %d, want %d", got, http.StatusNoContent)
+	}
+}
+
+func TestPerIPConcurrencyDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(PerIPConcurrency(0, nil))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// AllowedHosts rejects with 400 any request whose Host header names a host
+// not in hosts, so that a forged Host cannot end up in derived short URLs
+// or cached responses. Hosts are compared case-insensitively and without
+// ports. An empty list allows every host. Note that health probes must
+// then also send an allowed Host.
+func AllowedHosts(hosts []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		if h = hostname(strings.TrimSpace(h)); h != "" {
+			allowed[h] = true
+		}
+	}
+	return func(c *gin.Context) {
+		if len(allowed) > 0 && !allowed[hostname(c.Request.Host)] {
+			respond.AbortJSON(c, http.StatusBadRequest, models.Response{Error: "host not allowed"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// hostname returns hostport's host in lower case, without any port or IPv6
+// brackets.
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		hostport = host
+	}
+	return strings.ToLower(strings.Trim(hostport, "[]"))
+}
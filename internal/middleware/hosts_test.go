@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string
+		host     string
+		wantCode int
+	}{
+		{"empty list allows all", nil, "evil.example", http.StatusNoContent},
+		{"allowed", []string{"sho.rt"}, "sho.rt", http.StatusNoContent},
+		{"allowed with port", []string{"sho.rt"}, "sho.rt:8080", http.StatusNoContent},
+		{"case-insensitive", []string{"Sho.RT"}, "SHO.rt", http.StatusNoContent},
+		{"listed with port", []string{"sho.rt:443"}, "sho.rt", http.StatusNoContent},
+		{"ipv6", []string{"[::1]"}, "[::1]:8080", http.StatusNoContent},
+		{"disallowed", []string{"sho.rt"}, "evil.example", http.StatusBadRequest},
+		{"suffix is not enough", []string{"sho.rt"}, "evil.sho.rt", http.StatusBadRequest},
+		{"empty host", []string{"sho.rt"}, "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(AllowedHosts(tt.allowed))
+			r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
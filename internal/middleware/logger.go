@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/logging"
+)
+
+// Logger stores logger in the request context for logging.FromContext and
+// logs one line per request once it completes, with the request ID, user,
+// status and latency. Errors attached with c.Error are included, and
+// requests that end in a 5xx are logged at error level.
+func Logger(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+		c.Next()
+
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.Strings("errors", c.Errors.Errors()))
+		}
+		log := logging.FromContext(c.Request.Context())
+		if c.Writer.Status() >= 500 {
+			log.Error("request", fields...)
+			return
+		}
+		log.Info("request", fields...)
+	}
+}
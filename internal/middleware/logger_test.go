@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/maojcn/shortlink/internal/logging"
+)
+
+func TestLoggerIncludesRequestContext(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(), Logger(zap.New(core)), APIKeyAuth(fakeKeys{"good-key": 42}))
+	r.GET("/", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("handler")
+		_ = c.Error(errors.New("storage unavailable"))
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "good-key")
+	req.Header.Set(RequestIDHeader, "trace-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want 2", len(entries))
+	}
+	for _, e := range entries {
+		fields := e.ContextMap()
+		if fields["request_id"] != "trace-1" || fields["user_id"] != int64(42) {
+			t.Errorf("%q: fields = %v, want request_id and user_id", e.Message, fields)
+		}
+	}
+	access := entries[1]
+	if access.Level != zapcore.ErrorLevel || access.ContextMap()["status"] != int64(500) {
+		t.Errorf("access log = %v %v, want error level with status 500", access.Level, access.ContextMap())
+	}
+	if errs, _ := access.ContextMap()["errors"].([]interface{}); len(errs) != 1 || errs[0] != "storage unavailable" {
+		t.Errorf("errors = %v", access.ContextMap()["errors"])
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds request IDs accepted from clients.
+const maxRequestIDLen = 128
+
+// RequestID attaches a request ID to the request context with
+// reqctx.WithRequestID and echoes it in the X-Request-ID response header.
+// A well-formed X-Request-ID from the client is reused so a request can be
+// traced across services; otherwise a random ID is generated.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(reqctx.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// validRequestID accepts non-empty printable ASCII without spaces, so a
+// client-supplied ID cannot inject anything into log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
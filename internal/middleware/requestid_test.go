@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+func serveRequestID(header string) (sent, seen string) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/", func(c *gin.Context) {
+		seen = reqctx.RequestIDFromContext(c.Request.Context())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(RequestIDHeader, header)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Header().Get(RequestIDHeader), seen
+}
+
+func TestRequestIDReusesClientID(t *testing.T) {
+	sent, seen := serveRequestID("trace-1")
+	if sent != "trace-1" || seen != "trace-1" {
+		t.Errorf("header = %q, context = %q; want the client's ID", sent, seen)
+	}
+}
+
+func TestRequestIDGeneratesID(t *testing.T) {
+	for _, header := range []string{"", "has space", "new\tline", strings.Repeat("a", maxRequestIDLen+1)} {
+		sent, seen := serveRequestID(header)
+		if len(sent) != 32 || sent != seen || sent == header {
+			t.Errorf("client ID %q: header = %q, context = %q; want a fresh 32-char ID", header, sent, seen)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/servertiming"
+)
+
+// ServerTiming adds a Server-Timing header to every response, breaking the
+// request's time down into database calls and the rest, as recorded in
+// servertiming.Timings on the request context. It is meant for debugging
+// from a browser's network panel and reveals backend timings, so it is off
+// unless enabled; disabled, it adds nothing to the chain but a c.Next.
+//
+// The header must be set before the status line is written, so the
+// writer is wrapped to add it at that moment. It must come before Timeout
+// in the chain, which holds the response back until the handler is done.
+func ServerTiming(enabled bool) gin.HandlerFunc {
+	if !enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return func(c *gin.Context) {
+		ctx, timings := servertiming.NewContext(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		w := &timingWriter{ResponseWriter: c.Writer, timings: timings}
+		c.Writer = w
+		c.Next()
+		// A response without a body, such as c.Status(204), is only
+		// written by gin after the chain returns.
+		w.setHeader()
+		c.Writer = w.ResponseWriter
+	}
+}
+
+// timingWriter sets the Server-Timing header just before the response
+// header is written.
+type timingWriter struct {
+	gin.ResponseWriter
+	timings *servertiming.Timings
+	done    bool
+}
+
+func (w *timingWriter) setHeader() {
+	if w.done || w.ResponseWriter.Written() {
+		return
+	}
+	w.done = true
+	w.Header().Set("Server-Timing", w.timings.Header(time.Now()))
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	w.setHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timingWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/servertiming"
+)
+
+var serverTimingRE = regexp.MustCompile(`^db;dur=\d+\.\d{2};desc="queries: (\d+)", app;dur=\d+\.\d{2}, total;dur=\d+\.\d{2}$`)
+
+func TestServerTiming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ServerTiming(true), Timeout(time.Second))
+	r.GET("/json", func(c *gin.Context) {
+		servertiming.FromContext(c.Request.Context()).AddQuery(time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	r.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for path, wantQueries := range map[string]string{"/json": "1", "/empty": "0"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		h := w.Header().Get("Server-Timing")
+		m := serverTimingRE.FindStringSubmatch(h)
+		if m == nil {
+			t.Errorf("%s: Server-Timing = %q, not well-formed", path, h)
+			continue
+		}
+		if m[1] != wantQueries {
+			t.Errorf("%s: %s queries, want %s", path, m[1], wantQueries)
+		}
+	}
+}
+
+func TestServerTimingDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ServerTiming(false))
+	r.GET("/", func(c *gin.Context) {
+		if servertiming.FromContext(c.Request.Context()) != nil {
+			t.Error("request is timed with Server-Timing disabled")
+		}
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if h := w.Header().Get("Server-Timing"); h != "" {
+		t.Errorf("Server-Timing = %q, want none", h)
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reqctx"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// ShareTokenHeader carries a share token for clients that can set headers;
+// others pass it as the token query parameter.
+const ShareTokenHeader = "X-Share-Token"
+
+// ShareTokenRepository resolves share tokens. *repository.PostgresRepo
+// implements it.
+type ShareTokenRepository interface {
+	UserIDForShareToken(ctx context.Context, token, scope string) (int64, error)
+}
+
+// ShareTokenAuth rejects requests without a valid, unrevoked share token
+// of scope with 401, and otherwise attaches the token's owner like
+// APIKeyAuth. The token is read from ShareTokenHeader or, failing that,
+// the token query parameter. Use it only on read-only routes of scope:
+// share tokens travel in URLs and must not grant anything else.
+func ShareTokenAuth(repo ShareTokenRepository, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := shareTokenFromRequest(c)
+		if token == "" {
+			respond.AbortJSON(c, http.StatusUnauthorized, models.Response{Error: "missing share token"})
+			return
+		}
+
+		userID, err := repo.UserIDForShareToken(c.Request.Context(), token, scope)
+		if errors.Is(err, repository.ErrShareTokenNotFound) {
+			respond.AbortJSON(c, http.StatusUnauthorized, models.Response{Error: "invalid share token"})
+			return
+		}
+		if err != nil {
+			storageFailure(c, err, "failed to verify share token")
+			return
+		}
+
+		ctx := reqctx.WithUser(c.Request.Context(), reqctx.User{ID: userID})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func shareTokenFromRequest(c *gin.Context) string {
+	if token := strings.TrimSpace(c.GetHeader(ShareTokenHeader)); token != "" {
+		return token
+	}
+	return strings.TrimSpace(c.Query("token"))
+}
+
+// ShareTokenOrAPIKeyAuth is ShareTokenAuth for requests that carry a share
+// token and APIKeyAuth for the rest, for read-only routes of scope that
+// API clients use too.
+func ShareTokenOrAPIKeyAuth(shares ShareTokenRepository, scope string, keys APIKeyRepository) gin.HandlerFunc {
+	byToken, byKey := ShareTokenAuth(shares, scope), APIKeyAuth(keys)
+	return func(c *gin.Context) {
+		if shareTokenFromRequest(c) != "" {
+			byToken(c)
+			return
+		}
+		byKey(c)
+	}
+}
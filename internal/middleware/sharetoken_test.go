@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+	"github.com/maojcn/shortlink/internal/reqctx"
+)
+
+// fakeShareTokens maps tokens to their owner, all of scope feed.
+type fakeShareTokens map[string]int64
+
+func (f fakeShareTokens) UserIDForShareToken(_ context.Context, token, scope string) (int64, error) {
+	if token == "broken" {
+		return 0, errors.New("connection refused")
+	}
+	id, ok := f[token]
+	if !ok || scope != models.ShareScopeFeed {
+		return 0, repository.ErrShareTokenNotFound
+	}
+	return id, nil
+}
+
+func TestShareTokenAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    string
+		header   string
+		query    string
+		wantCode int
+	}{
+		{"header", models.ShareScopeFeed, "st_good", "", http.StatusNoContent},
+		{"query", models.ShareScopeFeed, "", "st_good", http.StatusNoContent},
+		{"missing", models.ShareScopeFeed, "", "", http.StatusUnauthorized},
+		{"unknown", models.ShareScopeFeed, "", "st_other", http.StatusUnauthorized},
+		{"wrong scope", "export", "", "st_good", http.StatusUnauthorized},
+		{"repository error", models.ShareScopeFeed, "broken", "", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			var user reqctx.User
+			r := gin.New()
+			r.Use(ShareTokenAuth(fakeShareTokens{"st_good": 42}, tt.scope))
+			r.GET("/", func(c *gin.Context) {
+				user, _ = reqctx.UserFromContext(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/?token="+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set(ShareTokenHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusNoContent && user.ID != 42 {
+				t.Errorf("user = %+v, want ID 42", user)
+			}
+		})
+	}
+}
+
+func TestShareTokenOrAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		wantCode int
+	}{
+		{"share token", ShareTokenHeader, "st_good", http.StatusNoContent},
+		{"api key", "X-API-Key", "good-key", http.StatusNoContent},
+		{"invalid share token", ShareTokenHeader, "good-key", http.StatusUnauthorized},
+		{"neither", "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ShareTokenOrAPIKeyAuth(fakeShareTokens{"st_good": 42}, models.ShareScopeFeed, fakeKeys{"good-key": 42}))
+			r.GET("/", func(c *gin.Context) {
+				if u, _ := reqctx.UserFromContext(c.Request.Context()); u.ID != 42 {
+					t.Errorf("user = %+v, want ID 42", u)
+				}
+				c.Status(http.StatusNoContent)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
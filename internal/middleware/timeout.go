@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/reqctx"
+	"github.com/maojcn/shortlink/internal/respond"
+)
+
+// timeoutMessage is the error of the response sent for a timed-out request.
+const timeoutMessage = "request timed out"
+
+// Timeout gives each request a deadline of d on its context. Handlers pass
+// that context to the repository, so a slow query is cancelled when the
+// deadline passes.
+//
+// The rest of the chain runs in its own goroutine and its response is held
+// back. If it finishes in time the response is sent as is. Otherwise the
+// client gets a 503 with the request ID as soon as the deadline passes,
+// and whatever the handler writes afterwards is discarded, so exactly one
+// response is written. Timeout still waits for the handler to return
+// before it does, because the gin.Context must not be reused while the
+// handler holds it; a panic in the handler is re-raised there for
+// gin.Recovery. A d of zero or less disables the deadline.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		// The fallback is encoded up front: once the handler runs, only
+		// it may touch c. A Response of strings always encodes.
+		contentType, fallback, _ := respond.Encode(c, models.Response{
+			Error:     timeoutMessage,
+			RequestID: reqctx.RequestIDFromContext(ctx),
+		})
+
+		w := &bufferedWriter{ResponseWriter: c.Writer, header: make(http.Header)}
+		c.Writer = w
+		done := make(chan struct{})
+		var panicked any
+		go func() {
+			defer close(done)
+			defer func() { panicked = recover() }()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				w.timeout(contentType, fallback)
+			}
+			<-done
+		}
+		c.Writer = w.ResponseWriter
+		if panicked != nil {
+			panic(panicked)
+		}
+		w.flush()
+	}
+}
+
+// bufferedWriter holds a handler's response until Timeout decides whether
+// to send it. Its methods are safe to call while Timeout writes the
+// fallback response.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	written  bool
+	timedOut bool
+}
+
+func (w *bufferedWriter) Header() http.Header { return w.header }
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// Flush is a no-op: nothing reaches the client before the handler returns.
+func (w *bufferedWriter) Flush() {}
+
+// timeout sends the 503 fallback to the underlying writer and marks the
+// held response as discarded. The response is complete and flushed, so the
+// client has it before the handler returns.
+func (w *bufferedWriter) timeout(contentType string, body []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+
+	dst := w.ResponseWriter
+	dst.Header().Set("Content-Type", contentType)
+	dst.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	dst.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = dst.Write(body)
+	dst.Flush()
+}
+
+// flush sends the held response to the underlying writer, unless the
+// fallback was sent instead.
+func (w *bufferedWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+
+	dst := w.ResponseWriter
+	for k, v := range w.header {
+		dst.Header()[k] = v
+	}
+	if w.status != 0 {
+		dst.WriteHeader(w.status)
+	}
+	if w.written {
+		dst.WriteHeaderNow()
+	}
+	if w.body.Len() > 0 {
+		_, _ = dst.Write(w.body.Bytes())
+	}
+}
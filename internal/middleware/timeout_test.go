@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+func serveWithTimeout(d time.Duration, h gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Timeout(d))
+	r.GET("/", h)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestTimeoutPassesFastResponses(t *testing.T) {
+	w := serveWithTimeout(time.Second, func(c *gin.Context) {
+		c.Header("X-Test", "yes")
+		c.JSON(http.StatusCreated, models.Response{Success: true})
+	})
+
+	if w.Code != http.StatusCreated || w.Header().Get("X-Test") != "yes" {
+		t.Errorf("status = %d, headers = %v", w.Code, w.Header())
+	}
+	if w.Body.String() != `{"success":true}` {
+		t.Errorf("body = %s", w.Body)
+	}
+}
+
+func TestTimeoutCancelsContextAndReturns503(t *testing.T) {
+	var ctxErr error
+	w := serveWithTimeout(10*time.Millisecond, func(c *gin.Context) {
+		// Stands in for a query that honours cancellation.
+		<-c.Request.Context().Done()
+		ctxErr = c.Request.Context().Err()
+		c.Header("X-Partial", "yes")
+		c.JSON(http.StatusOK, models.Response{Success: true})
+	})
+
+	if ctxErr != context.DeadlineExceeded {
+		t.Errorf("handler context error = %v, want %v", ctxErr, context.DeadlineExceeded)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if w.Header().Get("X-Partial") != "" {
+		t.Error("headers from the timed-out handler were sent")
+	}
+	var resp models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error != "request timed out" {
+		t.Errorf("body = %s", w.Body)
+	}
+}
+
+// lockedBuffer collects the test server's error log.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTimeoutRespondsBeforeHandlerWritesLate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	release := make(chan struct{})
+	handlerDone := make(chan struct{})
+	r := gin.New()
+	r.Use(RequestID(), Timeout(20*time.Millisecond))
+	r.GET("/", func(c *gin.Context) {
+		defer close(handlerDone)
+		// Ignores its context, then writes long after the deadline.
+		<-release
+		c.Header("X-Late", "yes")
+		c.JSON(http.StatusOK, models.Response{Success: true})
+		c.Writer.Flush()
+	})
+
+	var errLog lockedBuffer
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.ErrorLog = log.New(&errLog, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	select {
+	case <-handlerDone:
+		t.Fatal("response arrived only after the handler returned")
+	default:
+	}
+
+	if res.StatusCode != http.StatusServiceUnavailable || res.Header.Get("X-Late") != "" {
+		t.Errorf("status = %d, headers = %v", res.StatusCode, res.Header)
+	}
+	var resp models.Response
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error != "request timed out" || resp.RequestID != "req-123" {
+		t.Errorf("body = %s", body)
+	}
+
+	close(release)
+	<-handlerDone
+	srv.Close()
+	if strings.Contains(errLog.String(), "superfluous") {
+		t.Errorf("server log: %s", errLog.String())
+	}
+}
+
+func TestTimeoutRepanicsForRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusTeapot)
+	}), Timeout(time.Second))
+	r.GET("/", func(*gin.Context) { panic("boom") })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTeapot)
+	}
+}
+
+func TestTimeoutKeepsStatusWithoutBody(t *testing.T) {
+	w := serveWithTimeout(time.Second, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("status = %d, body = %q", w.Code, w.Body)
+	}
+}
+
+func TestTimeoutZeroDisablesDeadline(t *testing.T) {
+	w := serveWithTimeout(0, func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			t.Error("request has a deadline")
+		}
+		c.Status(http.StatusNoContent)
+	})
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
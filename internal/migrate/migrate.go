@@ -0,0 +1,99 @@
+// Package migrate applies the database schema. The migrations are embedded
+// in the binary and tracked in the schema_migrations table.
+package migrate
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "github.com/lib/pq" // registers the postgres driver
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrator runs the embedded migrations against one database.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New returns a Migrator for the Postgres database at dsn. Close it when
+// done.
+func New(dsn string) (*Migrator, error) {
+	src, err := embeddedSource()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to prepare migrations: %w", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("failed to prepare migrations: %w", err)
+	}
+	return &Migrator{m: m}, nil
+}
+
+func embeddedSource() (source.Driver, error) {
+	src, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+	return src, nil
+}
+
+// Up applies every pending migration. It is a no-op when the schema is
+// current.
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to migrate up: %w", err)
+	}
+	return nil
+}
+
+// Down reverts the last n applied migrations, or all of them if n is not
+// positive.
+func (m *Migrator) Down(n int) error {
+	var err error
+	if n > 0 {
+		err = m.m.Steps(-n)
+	} else {
+		err = m.m.Down()
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to migrate down: %w", err)
+	}
+	return nil
+}
+
+// Version returns the last applied migration, 0 if none has been. dirty
+// reports that that migration failed part-way and needs fixing by hand.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Close releases the database connection.
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
@@ -0,0 +1,51 @@
+//go:build integration
+
+package migrate
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMigrateUpDown needs a scratch Postgres database in
+// SHORTLINK_TEST_DSN; its tables are dropped. Run it with
+// "go test -tags integration ./internal/migrate".
+func TestMigrateUpDown(t *testing.T) {
+	dsn := os.Getenv("SHORTLINK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("SHORTLINK_TEST_DSN is not set")
+	}
+	m, err := New(dsn)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer m.Close()
+
+	wantVersion := func(want uint) {
+		t.Helper()
+		got, dirty, err := m.Version()
+		if err != nil || dirty || got != want {
+			t.Fatalf("Version = %d, %v, %v; want %d, clean", got, dirty, err, want)
+		}
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	wantVersion(5)
+	if err := m.Up(); err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+	if err := m.Down(1); err != nil {
+		t.Fatalf("Down(1): %v", err)
+	}
+	wantVersion(4)
+	if err := m.Down(0); err != nil {
+		t.Fatalf("Down(0): %v", err)
+	}
+	wantVersion(0)
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+	wantVersion(5)
+}
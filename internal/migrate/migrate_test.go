@@ -0,0 +1,52 @@
+package migrate
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedMigrationsArePaired(t *testing.T) {
+	src, err := embeddedSource()
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	count := 0
+	for {
+		count++
+		if r, _, err := src.ReadUp(version); err != nil {
+			t.Errorf("version %d has no up migration: %v", version, err)
+		} else {
+			r.Close()
+		}
+		if r, _, err := src.ReadDown(version); err != nil {
+			t.Errorf("version %d has no down migration: %v", version, err)
+		} else {
+			r.Close()
+		}
+		next, err := src.Next(version)
+		if err != nil {
+			break
+		}
+		version = next
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2*count {
+		t.Errorf("%d migration files for %d versions", len(files), count)
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".up.sql") && !strings.HasSuffix(f, ".down.sql") {
+			t.Errorf("%s is neither an up nor a down migration", f)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS short_links (
+    id           BIGSERIAL PRIMARY KEY,
+    code         TEXT NOT NULL UNIQUE,
+    original_url TEXT NOT NULL,
+    user_id      BIGINT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    key_hash   TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ NULL
+);
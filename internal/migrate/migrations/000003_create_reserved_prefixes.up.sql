@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS reserved_prefixes (
+    prefix     TEXT PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS share_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scope      TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS share_tokens_user_id_idx ON share_tokens (user_id);
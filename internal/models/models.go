@@ -0,0 +1,147 @@
+// Package models holds the API request/response types and database rows.
+package models
+
+import "time"
+
+// Response is the envelope every JSON endpoint returns. RequestID is only
+// set on errors the client may need to report, such as timeouts.
+type Response struct {
+	Success   bool        `json:"success"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Warnings  []string    `json:"warnings,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// ShortLink is a row of the short_links table. UserID is 0 for links
+// created without an authenticated user. OriginalURL holds the host in
+// punycode; DisplayURL, when set, is the same URL with a Unicode host.
+// ShortURL is the public URL of Code, filled in by the handlers.
+// Description is the owner's free-text note; redirects ignore it.
+type ShortLink struct {
+	ID          int64      `db:"id" json:"id"`
+	Code        string     `db:"code" json:"code"`
+	ShortURL    string     `db:"-" json:"short_url,omitempty"`
+	OriginalURL string     `db:"original_url" json:"original_url"`
+	DisplayURL  string     `db:"-" json:"display_url,omitempty"`
+	UserID      int64      `db:"user_id" json:"user_id,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	Description string     `db:"description" json:"description,omitempty"`
+}
+
+// NewShortLink is the input to PostgresRepo.CreateShortLink. UserID 0
+// means no owner, an empty CustomCode means a generated code and a nil
+// ExpiresAt means the link never expires. CreatedAt is only set by imports
+// that preserve the original creation time; nil means now. Description may
+// be empty.
+type NewShortLink struct {
+	OriginalURL string
+	UserID      int64
+	CustomCode  string
+	ExpiresAt   *time.Time
+	CreatedAt   *time.Time
+	Description string
+}
+
+// CreateShortLinkRequest is the body of POST /api/v1/links. CustomCode is
+// optional; when empty a code is derived from the link ID. ExpiresIn is an
+// optional lifetime accepted by duration.Parse, such as "24h" or "7d".
+// Description is an optional note of at most 500 characters. The body may
+// be JSON or form-encoded with the same field names.
+type CreateShortLinkRequest struct {
+	OriginalURL string `json:"original_url" form:"original_url" binding:"required,safeurl"`
+	CustomCode  string `json:"custom_code" form:"custom_code" binding:"omitempty,shortcode"`
+	ExpiresIn   string `json:"expires_in" form:"expires_in"`
+	Description string `json:"description" form:"description" binding:"max=500"`
+}
+
+// PaginatedResponse is the envelope list endpoints return. Page is
+// 1-based; TotalPages is 0 when there are no items.
+type PaginatedResponse struct {
+	Success    bool        `json:"success"`
+	Data       interface{} `json:"data"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	TotalItems int64       `json:"total_items"`
+	TotalPages int64       `json:"total_pages"`
+}
+
+// BatchItemResult is the outcome of one item of POST /api/v1/links/batch.
+// Index is the item's position in the request; Data is set on success and
+// Error otherwise.
+type BatchItemResult struct {
+	Index    int        `json:"index"`
+	Success  bool       `json:"success"`
+	Data     *ShortLink `json:"data,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Warnings []string   `json:"warnings,omitempty"`
+}
+
+// CheckLinksRequest is the optional body of POST /api/v1/me/links/check.
+// Without Codes every link of the caller is checked, up to the cap.
+type CheckLinksRequest struct {
+	Codes []string `json:"codes"`
+}
+
+// LinkCheckResult reports whether a link's destination is reachable.
+// Status is "ok", "broken" or "timeout"; HTTPStatus is omitted when no
+// response was received.
+type LinkCheckResult struct {
+	Code        string `json:"code"`
+	OriginalURL string `json:"original_url"`
+	Status      string `json:"status"`
+	HTTPStatus  int    `json:"http_status,omitempty"`
+}
+
+// HealthResponse is the body of the health endpoints. Status is "ok" or
+// "unavailable"; Checks has one entry per dependency, keyed by name.
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the outcome of pinging one dependency.
+type HealthCheck struct {
+	OK        bool    `json:"ok"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Share token scopes. A share token only authenticates requests to
+// endpoints of its scope.
+const (
+	ShareScopeFeed = "feed"
+)
+
+// ShareToken is a row of the share_tokens table: a long-lived, revocable
+// credential a user hands to clients that cannot send an API key, such as
+// feed readers. Token is only set in the response that mints it; only its
+// hash is stored.
+type ShareToken struct {
+	ID        int64     `db:"id" json:"id"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Scope     string    `db:"scope" json:"scope"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Token     string    `db:"-" json:"token,omitempty"`
+}
+
+// CreateShareTokenRequest is the body of POST /api/v1/me/share-tokens.
+type CreateShareTokenRequest struct {
+	Scope string `json:"scope" binding:"required,oneof=feed"`
+}
+
+// APIIndex is the body of GET /api/v1: the API version and the resource
+// collections under it, so integrators can discover the endpoints.
+type APIIndex struct {
+	Version     string          `json:"version"`
+	Collections []APICollection `json:"collections"`
+}
+
+// APICollection is one resource collection of the API. Href is relative to
+// the service root and Methods lists what the collection itself accepts.
+type APICollection struct {
+	Name    string   `json:"name"`
+	Href    string   `json:"href"`
+	Methods []string `json:"methods"`
+}
@@ -0,0 +1,160 @@
+// Package outbound builds the HTTP client used for requests to
+// user-supplied URLs.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+// ErrBlockedAddress is returned, wrapped, when a request would connect to
+// an address the client refuses.
+var ErrBlockedAddress = errors.New("outbound: address not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// netip does not count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewClient returns a client with cfg.Timeout. Unless
+// cfg.AllowPrivateNetworks is set it refuses to connect to loopback,
+// private, link-local, multicast and unspecified addresses. The check runs
+// on the resolved address of every connection, redirects included, so DNS
+// names pointing at internal hosts are refused too.
+//
+// With cfg.ProxyURL set, requests go through that proxy except for hosts
+// matching cfg.NoProxy. The proxy itself may be internal. The check then
+// moves to the target: its host is resolved before each proxied request
+// and refused if any of its addresses is internal. The proxy resolves the
+// host again on its own, so a name that changes its answer in between can
+// still reach an internal host; proxies in front of this service should
+// deny internal destinations as well.
+func NewClient(cfg config.OutboundConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	if cfg.AllowPrivateNetworks {
+		transport.Proxy = proxyFunc(cfg, nil)
+		return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+	}
+
+	guarded := &net.Dialer{Timeout: 5 * time.Second, Control: refuseInternal}
+	transport.DialContext = guarded.DialContext
+	if proxy := proxyAddr(cfg.ProxyURL); proxy != "" {
+		transport.Proxy = proxyFunc(cfg, refuseInternalHost)
+		transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == proxy {
+				return dialer.DialContext(ctx, network, address)
+			}
+			return guarded.DialContext(ctx, network, address)
+		}
+	}
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
+
+// NewTrustedClient returns a client with cfg.Timeout that uses the proxy
+// settings of cfg but connects to any address, for URLs the operator
+// configured, such as the webhook sink, rather than ones users supplied.
+func NewTrustedClient(cfg config.OutboundConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(cfg, nil)
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
+
+// proxyFunc returns the Transport.Proxy function for cfg: nil without a
+// proxy, and otherwise cfg.ProxyURL for every request whose host does not
+// match cfg.NoProxy, in the syntax of the NO_PROXY environment variable.
+// checkTarget, if set, vets the host of each proxied request.
+func proxyFunc(cfg config.OutboundConfig, checkTarget func(ctx context.Context, host string) error) func(*http.Request) (*url.URL, error) {
+	if cfg.ProxyURL == "" {
+		return nil
+	}
+	proxyFor := (&httpproxy.Config{
+		HTTPProxy:  cfg.ProxyURL,
+		HTTPSProxy: cfg.ProxyURL,
+		NoProxy:    strings.Join(cfg.NoProxy, ","),
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		proxy, err := proxyFor(req.URL)
+		if err != nil || proxy == nil {
+			return proxy, err
+		}
+		if checkTarget != nil {
+			if err := checkTarget(req.Context(), req.URL.Hostname()); err != nil {
+				return nil, err
+			}
+		}
+		return proxy, nil
+	}
+}
+
+// proxyAddr returns the host:port the transport dials for proxyURL, or ""
+// if there is no proxy.
+func proxyAddr(proxyURL string) string {
+	u, err := url.Parse(proxyURL)
+	if proxyURL == "" || err != nil {
+		return ""
+	}
+	if port := u.Port(); port != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// refuseInternalHost resolves host and fails if any of its addresses is
+// internal.
+func refuseInternalHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if IsInternal(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, addr)
+		}
+	}
+	return nil
+}
+
+func refuseInternal(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if IsInternal(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+	return nil
+}
+
+// IsInternal reports whether addr is not a public unicast address.
+func IsInternal(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr)
+}
@@ -0,0 +1,131 @@
+package outbound
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+func TestIsInternal(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := IsInternal(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsInternal(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := NewClient(config.OutboundConfig{Timeout: time.Second}).Get(srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("error = %v, want ErrBlockedAddress", err)
+	}
+}
+
+func TestNewClientAllowPrivateNetworks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	resp, err := NewClient(config.OutboundConfig{Timeout: time.Second, AllowPrivateNetworks: true}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewClientUsesProxy(t *testing.T) {
+	client := NewClient(config.OutboundConfig{
+		Timeout:  time.Second,
+		ProxyURL: "http://proxy.internal:3128",
+		NoProxy:  []string{"direct.example", "198.51.100.0/24"},
+	})
+	proxy := client.Transport.(*http.Transport).Proxy
+	if proxy == nil {
+		t.Fatal("transport has no proxy")
+	}
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"http://93.184.216.34/", "http://proxy.internal:3128"},
+		{"https://93.184.216.34/", "http://proxy.internal:3128"},
+		{"http://direct.example/", ""},
+		{"http://198.51.100.7/", ""},
+	}
+	for _, tt := range tests {
+		got, err := proxy(httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if err != nil {
+			t.Errorf("Proxy(%s): %v", tt.target, err)
+			continue
+		}
+		if (got == nil && tt.want != "") || (got != nil && got.String() != tt.want) {
+			t.Errorf("Proxy(%s) = %v, want %q", tt.target, got, tt.want)
+		}
+	}
+
+	if p := NewClient(config.OutboundConfig{Timeout: time.Second}).Transport.(*http.Transport).Proxy; p != nil {
+		t.Error("transport has a proxy without ProxyURL")
+	}
+}
+
+func TestNewClientRefusesInternalTargetThroughProxy(t *testing.T) {
+	proxied := false
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { proxied = true }))
+	defer proxy.Close()
+
+	client := NewClient(config.OutboundConfig{Timeout: time.Second, ProxyURL: proxy.URL})
+	_, err := client.Get("http://10.0.0.1/")
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("error = %v, want ErrBlockedAddress", err)
+	}
+	if proxied {
+		t.Error("request reached the proxy")
+	}
+}
+
+func TestNewClientSendsThroughProxy(t *testing.T) {
+	var target string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { target = r.URL.String() }))
+	defer proxy.Close()
+
+	for name, client := range map[string]*http.Client{
+		"NewClient":        NewClient(config.OutboundConfig{Timeout: time.Second, AllowPrivateNetworks: true, ProxyURL: proxy.URL}),
+		"NewTrustedClient": NewTrustedClient(config.OutboundConfig{Timeout: time.Second, ProxyURL: proxy.URL}),
+	} {
+		target = ""
+		resp, err := client.Get("http://links.example/page")
+		if err != nil {
+			t.Fatalf("%s: Get: %v", name, err)
+		}
+		resp.Body.Close()
+		if target != "http://links.example/page" {
+			t.Errorf("%s: proxy saw %q, want the absolute target URL", name, target)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrAPIKeyNotFound is returned by UserIDForAPIKey when the key is unknown
+// or revoked.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+const userIDForAPIKeySQL = `
+	SELECT user_id FROM api_keys
+	WHERE key_hash = $1 AND revoked_at IS NULL`
+
+// HashAPIKey returns the hex SHA-256 digest stored in api_keys.key_hash.
+// Keys themselves are never stored.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// UserIDForAPIKey returns the ID of the user owning key.
+func (r *PostgresRepo) UserIDForAPIKey(ctx context.Context, key string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "UserIDForAPIKey", "SELECT", "api_keys")
+	defer func() { endSpan(span, err) }()
+	var userID int64
+	err = retryRead(ctx, func() error {
+		return r.db.GetContext(ctx, &userID, userIDForAPIKeySQL, HashAPIKey(key))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up api key: %w", classify(err))
+	}
+	return userID, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var apiKeyQuery = regexp.QuoteMeta(userIDForAPIKeySQL)
+
+func TestHashAPIKey(t *testing.T) {
+	// sha256("abc")
+	const want = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := HashAPIKey("abc"); got != want {
+		t.Errorf("HashAPIKey = %s, want %s", got, want)
+	}
+}
+
+func TestUserIDForAPIKey(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(apiKeyQuery).
+		WithArgs(HashAPIKey("sk_live")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(7)))
+
+	id, err := repo.UserIDForAPIKey(context.Background(), "sk_live")
+	if err != nil {
+		t.Fatalf("UserIDForAPIKey: %v", err)
+	}
+	if id != 7 {
+		t.Errorf("user id = %d, want 7", id)
+	}
+}
+
+func TestUserIDForAPIKeyUnknown(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(apiKeyQuery).WillReturnError(sql.ErrNoRows)
+
+	if _, err := repo.UserIDForAPIKey(context.Background(), "nope"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("error = %v, want ErrAPIKeyNotFound", err)
+	}
+}
+
+func TestUserIDForAPIKeyDatabaseError(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	dbErr := errors.New("connection refused")
+	mock.ExpectQuery(apiKeyQuery).WillReturnError(dbErr)
+
+	_, err := repo.UserIDForAPIKey(context.Background(), "k")
+	if !errors.Is(err, dbErr) || errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("error = %v, want %v", err, dbErr)
+	}
+}
@@ -0,0 +1,81 @@
+// Package repository implements persistence on top of Postgres.
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/lib/pq/pqerror"
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+// maxConnectDelay caps the backoff between connection attempts.
+const maxConnectDelay = 30 * time.Second
+
+// PostgresRepo wraps the Postgres connection pool. Errors caused by a lost
+// connection rather than the query wrap ErrUnavailable; lookups are retried
+// once on another connection before failing so.
+type PostgresRepo struct {
+	db  *sqlx.DB
+	seq SequenceSource
+}
+
+// NewPostgresRepo connects to the database described by cfg and verifies
+// the connection. New short link codes are generated from seq. Failed attempts are logged at warn level and retried
+// with exponential backoff, so the service survives Postgres starting
+// after it; the last error is returned once cfg.ConnectMaxAttempts is
+// exhausted.
+func NewPostgresRepo(cfg config.DatabaseConfig, seq SequenceSource, logger *zap.Logger) (*PostgresRepo, error) {
+	db, err := connectWithRetry(cfg, logger, func(dsn string) (*sqlx.DB, error) {
+		return sqlx.Connect("postgres", dsn)
+	}, time.Sleep)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresRepo{db: db, seq: seq}, nil
+}
+
+// connectWithRetry calls connect until it succeeds or cfg.ConnectMaxAttempts
+// attempts have failed, sleeping between attempts.
+func connectWithRetry(cfg config.DatabaseConfig, logger *zap.Logger, connect func(dsn string) (*sqlx.DB, error), sleep func(time.Duration)) (*sqlx.DB, error) {
+	attempts := max(cfg.ConnectMaxAttempts, 1)
+	delay := cfg.ConnectBaseDelay
+	for attempt := 1; ; attempt++ {
+		db, err := connect(cfg.DSN)
+		if err == nil {
+			return db, nil
+		}
+		if attempt == attempts {
+			return nil, fmt.Errorf("failed to connect to postgres after %d attempts: %w", attempts, err)
+		}
+		logger.Warn("postgres connection failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", attempts),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+		sleep(delay)
+		delay = min(delay*2, maxConnectDelay)
+	}
+}
+
+// Close releases the connection pool.
+func (r *PostgresRepo) Close() error {
+	return r.db.Close()
+}
+
+// Ping checks that the database is reachable.
+func (r *PostgresRepo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqerror.UniqueViolation
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+func TestConnectWithRetryBacksOff(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	cfg := config.DatabaseConfig{DSN: "postgres://db", ConnectMaxAttempts: 5, ConnectBaseDelay: time.Second}
+	want := &sqlx.DB{}
+
+	calls := 0
+	connect := func(dsn string) (*sqlx.DB, error) {
+		calls++
+		if dsn != cfg.DSN {
+			t.Errorf("dsn = %q, want %q", dsn, cfg.DSN)
+		}
+		if calls < 4 {
+			return nil, errors.New("connection refused")
+		}
+		return want, nil
+	}
+	var slept []time.Duration
+	sleep := func(d time.Duration) { slept = append(slept, d) }
+
+	db, err := connectWithRetry(cfg, zap.New(core), connect, sleep)
+	if err != nil {
+		t.Fatalf("connectWithRetry: %v", err)
+	}
+	if db != want {
+		t.Error("returned a different *sqlx.DB")
+	}
+	wantSlept := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if len(slept) != len(wantSlept) {
+		t.Fatalf("slept %v, want %v", slept, wantSlept)
+	}
+	for i := range wantSlept {
+		if slept[i] != wantSlept[i] {
+			t.Fatalf("slept %v, want %v", slept, wantSlept)
+		}
+	}
+	if logs.Len() != 3 {
+		t.Errorf("logged %d warnings, want 3", logs.Len())
+	}
+}
+
+func TestConnectWithRetryGivesUp(t *testing.T) {
+	cfg := config.DatabaseConfig{ConnectMaxAttempts: 3, ConnectBaseDelay: 20 * time.Second}
+	dbErr := errors.New("connection refused")
+
+	calls := 0
+	var slept []time.Duration
+	_, err := connectWithRetry(cfg, zap.NewNop(),
+		func(string) (*sqlx.DB, error) { calls++; return nil, dbErr },
+		func(d time.Duration) { slept = append(slept, d) })
+
+	if !errors.Is(err, dbErr) {
+		t.Errorf("error = %v, want %v", err, dbErr)
+	}
+	if calls != 3 {
+		t.Errorf("connect called %d times, want 3", calls)
+	}
+	if len(slept) != 2 || slept[1] != maxConnectDelay {
+		t.Errorf("slept %v, want [20s %v]", slept, maxConnectDelay)
+	}
+}
+
+func TestConnectWithRetryAtLeastOnce(t *testing.T) {
+	calls := 0
+	_, err := connectWithRetry(config.DatabaseConfig{}, zap.NewNop(),
+		func(string) (*sqlx.DB, error) { calls++; return nil, errors.New("down") },
+		func(time.Duration) { t.Error("slept with a single attempt") })
+	if err == nil || calls != 1 {
+		t.Errorf("calls = %d, err = %v; want one failed attempt", calls, err)
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+// SequenceSource hands out the ID of the next short link and the number its
+// code is generated from.
+type SequenceSource interface {
+	Next(ctx context.Context, q sqlx.QueryerContext) (id, value int64, err error)
+}
+
+// NewSequenceSource returns the SequenceSource selected by cfg.CodeSource:
+// "serial" or "obfuscated".
+func NewSequenceSource(cfg config.LinkConfig) (SequenceSource, error) {
+	switch cfg.CodeSource {
+	case "serial":
+		return SerialSource{}, nil
+	case "obfuscated":
+		if cfg.CodeKey == "" {
+			return nil, errors.New("obfuscated code source requires LINKS_CODE_KEY")
+		}
+		return NewObfuscatedSource([]byte(cfg.CodeKey)), nil
+	default:
+		return nil, fmt.Errorf("unknown code source %q", cfg.CodeSource)
+	}
+}
+
+// SerialSource derives codes directly from the row ID, so code order
+// follows creation order and reveals how many links exist.
+type SerialSource struct{}
+
+// Next implements SequenceSource.
+func (SerialSource) Next(ctx context.Context, q sqlx.QueryerContext) (id, value int64, err error) {
+	if err := sqlx.GetContext(ctx, q, &id, nextShortLinkIDSQL); err != nil {
+		return 0, 0, err
+	}
+	return id, id, nil
+}
+
+const (
+	// obfuscatedBits is the width of the permuted domain. IDs must stay
+	// below 1<<obfuscatedBits, which keeps codes at most 7 characters.
+	obfuscatedBits = 40
+	halfBits       = obfuscatedBits / 2
+	halfMask       = 1<<halfBits - 1
+	feistelRounds  = 4
+)
+
+// ObfuscatedSource maps the row ID through a keyed permutation of
+// [0, 1<<40) before it is encoded, so consecutive links get unrelated codes
+// while codes stay unique without a collision check.
+type ObfuscatedSource struct {
+	key []byte
+}
+
+// NewObfuscatedSource returns an ObfuscatedSource keyed by key. Changing
+// the key changes every code generated afterwards, and may collide with
+// codes generated before.
+func NewObfuscatedSource(key []byte) *ObfuscatedSource {
+	return &ObfuscatedSource{key: key}
+}
+
+// Next implements SequenceSource.
+func (s *ObfuscatedSource) Next(ctx context.Context, q sqlx.QueryerContext) (id, value int64, err error) {
+	if err := sqlx.GetContext(ctx, q, &id, nextShortLinkIDSQL); err != nil {
+		return 0, 0, err
+	}
+	if id < 0 || id >= 1<<obfuscatedBits {
+		return 0, 0, fmt.Errorf("short link id %d is outside the obfuscated range", id)
+	}
+	return id, s.permute(id), nil
+}
+
+// permute is a balanced Feistel network over 40 bits. It is a bijection for
+// any round function, so distinct IDs always map to distinct values.
+func (s *ObfuscatedSource) permute(id int64) int64 {
+	left, right := uint32(id>>halfBits), uint32(id&halfMask)
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^s.round(round, right)
+	}
+	return int64(left)<<halfBits | int64(right)
+}
+
+func (s *ObfuscatedSource) round(round int, half uint32) uint32 {
+	var buf [5]byte
+	buf[0] = byte(round)
+	binary.BigEndian.PutUint32(buf[1:], half)
+	h := sha256.New()
+	h.Write(s.key)
+	h.Write(buf[:])
+	return binary.BigEndian.Uint32(h.Sum(nil)) & halfMask
+}
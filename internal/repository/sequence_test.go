@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+func TestSerialSource(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expectNextID(mock, 42)
+
+	id, value, err := SerialSource{}.Next(context.Background(), repo.db)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if id != 42 || value != 42 {
+		t.Errorf("Next = (%d, %d), want (42, 42)", id, value)
+	}
+}
+
+func TestObfuscatedSourceCodesAreNonSequential(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	src := NewObfuscatedSource([]byte("secret"))
+
+	const n = 50
+	seen := make(map[int64]bool)
+	var values []int64
+	for id := int64(1); id <= n; id++ {
+		expectNextID(mock, id)
+		gotID, value, err := src.Next(context.Background(), repo.db)
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if gotID != id {
+			t.Errorf("id = %d, want %d", gotID, id)
+		}
+		if value < 0 || value >= 1<<obfuscatedBits {
+			t.Errorf("value %d out of range", value)
+		}
+		if seen[value] {
+			t.Fatalf("value %d generated twice", value)
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+
+	ascending, adjacent := 0, 0
+	for i := 1; i < len(values); i++ {
+		if values[i] > values[i-1] {
+			ascending++
+		}
+		if d := values[i] - values[i-1]; d == 1 || d == -1 {
+			adjacent++
+		}
+	}
+	if ascending == n-1 || adjacent > 0 {
+		t.Errorf("values look sequential: %v", values)
+	}
+}
+
+func TestObfuscatedSourcePermutation(t *testing.T) {
+	a := NewObfuscatedSource([]byte("key-a"))
+	b := NewObfuscatedSource([]byte("key-b"))
+
+	if a.permute(12345) != a.permute(12345) {
+		t.Error("permute is not deterministic")
+	}
+	if a.permute(12345) == b.permute(12345) {
+		t.Error("different keys produced the same value")
+	}
+
+	// A Feistel network is a bijection; check it on a block of IDs.
+	seen := make(map[int64]bool)
+	for id := int64(1<<obfuscatedBits - 5000); id < 1<<obfuscatedBits; id++ {
+		v := a.permute(id)
+		if seen[v] {
+			t.Fatalf("permute(%d) = %d collides", id, v)
+		}
+		seen[v] = true
+	}
+}
+
+func TestObfuscatedSourceRejectsLargeIDs(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expectNextID(mock, 1<<obfuscatedBits)
+
+	if _, _, err := NewObfuscatedSource([]byte("k")).Next(context.Background(), repo.db); err == nil {
+		t.Error("Next accepted an id outside the permuted range")
+	}
+}
+
+func TestNewSequenceSource(t *testing.T) {
+	if src, err := NewSequenceSource(config.LinkConfig{CodeSource: "serial"}); err != nil {
+		t.Errorf("serial: %v", err)
+	} else if _, ok := src.(SerialSource); !ok {
+		t.Errorf("serial: got %T", src)
+	}
+	if src, err := NewSequenceSource(config.LinkConfig{CodeSource: "obfuscated", CodeKey: "k"}); err != nil {
+		t.Errorf("obfuscated: %v", err)
+	} else if _, ok := src.(*ObfuscatedSource); !ok {
+		t.Errorf("obfuscated: got %T", src)
+	}
+	if _, err := NewSequenceSource(config.LinkConfig{CodeSource: "obfuscated"}); err == nil {
+		t.Error("obfuscated without key: no error")
+	}
+	if _, err := NewSequenceSource(config.LinkConfig{CodeSource: "random"}); err == nil {
+		t.Error("unknown source: no error")
+	}
+}
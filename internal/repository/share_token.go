@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+// ErrShareTokenNotFound is returned when a share token is unknown, revoked,
+// of another scope, or, for RevokeShareToken, owned by someone else.
+var ErrShareTokenNotFound = errors.New("share token not found")
+
+// shareTokenPrefix marks share tokens so they are recognizable in logs and
+// secret scanners.
+const shareTokenPrefix = "st_"
+
+const insertShareTokenSQL = `
+	INSERT INTO share_tokens (user_id, token_hash, scope)
+	VALUES ($1, $2, $3)
+	RETURNING id, user_id, scope, created_at`
+
+// CreateShareToken mints a token of scope for userID and returns it with
+// its row. The token is only ever returned here; like API keys it is
+// stored as its HashAPIKey digest.
+func (r *PostgresRepo) CreateShareToken(ctx context.Context, userID int64, scope string) (_ *models.ShareToken, err error) {
+	ctx, span := startSpan(ctx, "CreateShareToken", "INSERT", "share_tokens")
+	defer func() { endSpan(span, err) }()
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate share token: %w", err)
+	}
+	token := shareTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	var st models.ShareToken
+	if err := r.db.GetContext(ctx, &st, insertShareTokenSQL, userID, HashAPIKey(token), scope); err != nil {
+		return nil, fmt.Errorf("failed to create share token: %w", classify(err))
+	}
+	st.Token = token
+	return &st, nil
+}
+
+const revokeShareTokenSQL = `
+	UPDATE share_tokens SET revoked_at = now()
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+// RevokeShareToken revokes userID's token id. It returns
+// ErrShareTokenNotFound if userID has no such unrevoked token.
+func (r *PostgresRepo) RevokeShareToken(ctx context.Context, userID, id int64) (err error) {
+	ctx, span := startSpan(ctx, "RevokeShareToken", "UPDATE", "share_tokens")
+	defer func() { endSpan(span, err) }()
+	res, err := r.db.ExecContext(ctx, revokeShareTokenSQL, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share token: %w", classify(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke share token: %w", classify(err))
+	}
+	if n == 0 {
+		return ErrShareTokenNotFound
+	}
+	return nil
+}
+
+const userIDForShareTokenSQL = `
+	SELECT user_id FROM share_tokens
+	WHERE token_hash = $1 AND scope = $2 AND revoked_at IS NULL`
+
+// UserIDForShareToken returns the ID of the user owning token, provided
+// the token is unrevoked and of scope.
+func (r *PostgresRepo) UserIDForShareToken(ctx context.Context, token, scope string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "UserIDForShareToken", "SELECT", "share_tokens")
+	defer func() { endSpan(span, err) }()
+	var userID int64
+	err = retryRead(ctx, func() error {
+		return r.db.GetContext(ctx, &userID, userIDForShareTokenSQL, HashAPIKey(token), scope)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrShareTokenNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up share token: %w", classify(err))
+	}
+	return userID, nil
+}
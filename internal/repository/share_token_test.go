@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+var (
+	insertShareTokenQuery = regexp.QuoteMeta(insertShareTokenSQL)
+	revokeShareTokenQuery = regexp.QuoteMeta(revokeShareTokenSQL)
+	shareTokenQuery       = regexp.QuoteMeta(userIDForShareTokenSQL)
+)
+
+// hashOf matches the share token hash argument and records the token
+// hashes it sees.
+type hashOf struct{ got *string }
+
+func (h hashOf) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*h.got = s
+	return ok && len(s) == 64
+}
+
+func TestCreateShareToken(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	var hash string
+	mock.ExpectQuery(insertShareTokenQuery).
+		WithArgs(int64(7), hashOf{&hash}, models.ShareScopeFeed).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "scope", "created_at"}).
+			AddRow(int64(3), int64(7), models.ShareScopeFeed, time.Now()))
+
+	st, err := repo.CreateShareToken(context.Background(), 7, models.ShareScopeFeed)
+	if err != nil {
+		t.Fatalf("CreateShareToken: %v", err)
+	}
+	if st.ID != 3 || st.UserID != 7 || st.Scope != models.ShareScopeFeed {
+		t.Errorf("token = %+v", st)
+	}
+	if !strings.HasPrefix(st.Token, shareTokenPrefix) || len(st.Token) < 40 {
+		t.Errorf("Token = %q, want a long st_ token", st.Token)
+	}
+	if hash != HashAPIKey(st.Token) {
+		t.Error("stored hash is not the token's digest")
+	}
+}
+
+func TestRevokeShareToken(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectExec(revokeShareTokenQuery).WithArgs(int64(3), int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(revokeShareTokenQuery).WithArgs(int64(3), int64(8)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.RevokeShareToken(context.Background(), 7, 3); err != nil {
+		t.Errorf("RevokeShareToken: %v", err)
+	}
+	if err := repo.RevokeShareToken(context.Background(), 8, 3); !errors.Is(err, ErrShareTokenNotFound) {
+		t.Errorf("revoking another user's token: error = %v, want %v", err, ErrShareTokenNotFound)
+	}
+}
+
+func TestUserIDForShareToken(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(shareTokenQuery).
+		WithArgs(HashAPIKey("st_abc"), models.ShareScopeFeed).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(7)))
+	mock.ExpectQuery(shareTokenQuery).
+		WithArgs(HashAPIKey("st_abc"), "export").
+		WillReturnError(sql.ErrNoRows)
+
+	id, err := repo.UserIDForShareToken(context.Background(), "st_abc", models.ShareScopeFeed)
+	if err != nil || id != 7 {
+		t.Errorf("UserIDForShareToken = %d, %v; want 7", id, err)
+	}
+	if _, err := repo.UserIDForShareToken(context.Background(), "st_abc", "export"); !errors.Is(err, ErrShareTokenNotFound) {
+		t.Errorf("other scope: error = %v, want %v", err, ErrShareTokenNotFound)
+	}
+}
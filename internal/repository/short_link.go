@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/shortcode"
+)
+
+var (
+	// ErrCodeTaken is returned by CreateShortLink when the requested custom
+	// code is already in use.
+	ErrCodeTaken = errors.New("short code already taken")
+	// ErrPrefixReserved is returned by CreateShortLink when the requested
+	// custom code starts with a prefix reserved for another user.
+	ErrPrefixReserved = errors.New("short code prefix reserved")
+	// ErrLinkNotFound is returned by GetShortLinkByCode when no link has the
+	// code. It wraps sql.ErrNoRows, so callers checking for either match.
+	ErrLinkNotFound = fmt.Errorf("short link not found: %w", sql.ErrNoRows)
+)
+
+// maxCodeAttempts bounds how many IDs CreateShortLink tries when a
+// generated code collides with an existing custom code.
+const maxCodeAttempts = 3
+
+const nextShortLinkIDSQL = `SELECT nextval('short_links_id_seq')`
+
+const insertShortLinkSQL = `
+	INSERT INTO short_links (id, code, original_url, user_id, expires_at, created_at, description)
+	VALUES ($1, $2, $3, NULLIF($4::bigint, 0), $5, COALESCE($6::timestamptz, now()), $7)
+	RETURNING id, code, original_url, COALESCE(user_id, 0) AS user_id, created_at, expires_at, description`
+
+// insertCustomShortLinkSQL inserts nothing when the code starts with a
+// prefix in reserved_prefixes that belongs to someone else.
+const insertCustomShortLinkSQL = `
+	INSERT INTO short_links (id, code, original_url, user_id, expires_at, created_at, description)
+	SELECT $1::bigint, $2::text, $3::text, NULLIF($4::bigint, 0), $5::timestamptz, COALESCE($6::timestamptz, now()), $7::text
+	WHERE NOT EXISTS (
+		SELECT 1 FROM reserved_prefixes
+		WHERE left($2::text, length(prefix)) = prefix
+		  AND user_id IS DISTINCT FROM NULLIF($4::bigint, 0))
+	RETURNING id, code, original_url, COALESCE(user_id, 0) AS user_id, created_at, expires_at, description`
+
+// CreateShortLink stores in and returns the created row. A UserID of 0 is
+// stored as NULL and a nil CreatedAt as the current time.
+//
+// If in.CustomCode is non-empty it is used as the code. ErrCodeTaken is
+// returned when another link already has it, and ErrPrefixReserved when it
+// starts with a prefix reserved for another user. Otherwise the code is the
+// base62 encoding of the value the repository's SequenceSource pairs with
+// the row ID; the ID is reserved from short_links_id_seq (backing the
+// BIGSERIAL id column) first so the row is inserted complete. Generated
+// codes only collide with custom codes that happen to be valid base62; on
+// such a collision the next ID is tried.
+//
+// Availability is never checked ahead of the insert: the unique constraint
+// on code decides, so of concurrent requests for one code exactly one
+// succeeds.
+func (r *PostgresRepo) CreateShortLink(ctx context.Context, in models.NewShortLink) (_ *models.ShortLink, err error) {
+	ctx, span := startSpan(ctx, "CreateShortLink", "INSERT", "short_links")
+	defer func() { endSpan(span, err) }()
+	return r.createShortLink(ctx, r.db, in, false)
+}
+
+// CreateShortLinkTx is CreateShortLink within tx, for use with WithTx. A
+// rejected custom code leaves tx usable.
+func (r *PostgresRepo) CreateShortLinkTx(ctx context.Context, tx *sqlx.Tx, in models.NewShortLink) (_ *models.ShortLink, err error) {
+	ctx, span := startSpan(ctx, "CreateShortLinkTx", "INSERT", "short_links")
+	defer func() { endSpan(span, err) }()
+	return r.createShortLink(ctx, tx, in, true)
+}
+
+// createShortLink implements CreateShortLink on q. Inside a transaction,
+// inTx must be set: a unique violation aborts the whole transaction, so
+// each insert then runs under a savepoint that is rolled back on conflict.
+func (r *PostgresRepo) createShortLink(ctx context.Context, q sqlx.ExtContext, in models.NewShortLink, inTx bool) (*models.ShortLink, error) {
+	for attempt := 1; ; attempt++ {
+		id, value, err := r.seq.Next(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve short link id: %w", classify(err))
+		}
+		code, query := in.CustomCode, insertCustomShortLinkSQL
+		if code == "" {
+			code, query = shortcode.Encode(value), insertShortLinkSQL
+		}
+
+		var link models.ShortLink
+		if inTx {
+			err = withSavepoint(ctx, q, func() error {
+				return sqlx.GetContext(ctx, q, &link, query, id, code, in.OriginalURL, in.UserID, in.ExpiresAt, in.CreatedAt, in.Description)
+			})
+		} else {
+			err = sqlx.GetContext(ctx, q, &link, query, id, code, in.OriginalURL, in.UserID, in.ExpiresAt, in.CreatedAt, in.Description)
+		}
+		switch {
+		case err == nil:
+			return &link, nil
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrPrefixReserved
+		case isUniqueViolation(err) && in.CustomCode != "":
+			return nil, ErrCodeTaken
+		case isUniqueViolation(err) && attempt < maxCodeAttempts:
+			continue
+		default:
+			return nil, fmt.Errorf("failed to create short link: %w", classify(err))
+		}
+	}
+}
+
+// withSavepoint runs fn under a savepoint and rolls back to it if fn fails
+// with a unique violation, leaving the surrounding transaction usable. fn
+// returning sql.ErrNoRows does not abort the transaction and is passed
+// through once the savepoint is released.
+func withSavepoint(ctx context.Context, q sqlx.ExecerContext, fn func() error) error {
+	if _, err := q.ExecContext(ctx, `SAVEPOINT short_link`); err != nil {
+		return err
+	}
+	err := fn()
+	if isUniqueViolation(err) {
+		if _, rbErr := q.ExecContext(ctx, `ROLLBACK TO SAVEPOINT short_link`); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if _, relErr := q.ExecContext(ctx, `RELEASE SAVEPOINT short_link`); relErr != nil {
+		return relErr
+	}
+	return err
+}
+
+// BatchResult is the outcome of one item of BatchCreateShortLinks: either
+// Link or Err is set. Err is ErrCodeTaken or ErrPrefixReserved.
+type BatchResult struct {
+	Link *models.ShortLink
+	Err  error
+}
+
+// BatchCreateShortLinks creates items in a single transaction and returns
+// one result per item, in order. Items rejected for their custom code are
+// reported in their result and do not affect the others; any other error
+// rolls back the whole batch.
+func (r *PostgresRepo) BatchCreateShortLinks(ctx context.Context, items []models.NewShortLink) (_ []BatchResult, err error) {
+	ctx, span := startSpan(ctx, "BatchCreateShortLinks", "INSERT", "short_links")
+	defer func() { endSpan(span, err) }()
+	results := make([]BatchResult, len(items))
+	err = r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		for i, in := range items {
+			link, err := r.CreateShortLinkTx(ctx, tx, in)
+			if errors.Is(err, ErrCodeTaken) || errors.Is(err, ErrPrefixReserved) {
+				results[i].Err = err
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			results[i].Link = link
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+const getShortLinkByCodeSQL = `
+	SELECT id, code, original_url, COALESCE(user_id, 0) AS user_id, created_at, expires_at, description
+	FROM short_links
+	WHERE code = $1`
+
+// GetShortLinkByCode returns the link with code, or ErrLinkNotFound if
+// there is none.
+func (r *PostgresRepo) GetShortLinkByCode(ctx context.Context, code string) (_ *models.ShortLink, err error) {
+	ctx, span := startSpan(ctx, "GetShortLinkByCode", "SELECT", "short_links")
+	defer func() { endSpan(span, err) }()
+	var link models.ShortLink
+	err = retryRead(ctx, func() error {
+		return r.db.GetContext(ctx, &link, getShortLinkByCodeSQL, code)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short link: %w", classify(err))
+	}
+	return &link, nil
+}
+
+const listUserShortLinksSQL = `
+	SELECT id, code, original_url, COALESCE(user_id, 0) AS user_id, created_at, expires_at, description
+	FROM short_links
+	WHERE user_id = $1 AND ($2::text[] IS NULL OR code = ANY($2))
+	ORDER BY id DESC
+	LIMIT $3`
+
+// ListUserShortLinks returns up to limit links owned by userID, newest
+// first. If codes is non-nil only links with those codes are returned;
+// codes owned by other users are silently skipped.
+func (r *PostgresRepo) ListUserShortLinks(ctx context.Context, userID int64, codes []string, limit int) (_ []models.ShortLink, err error) {
+	ctx, span := startSpan(ctx, "ListUserShortLinks", "SELECT", "short_links")
+	defer func() { endSpan(span, err) }()
+	var links []models.ShortLink
+	err = retryRead(ctx, func() error {
+		links = nil
+		return r.db.SelectContext(ctx, &links, listUserShortLinksSQL, userID, pq.Array(codes), limit)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list short links: %w", classify(err))
+	}
+	return links, nil
+}
+
+const deleteExpiredShortLinksSQL = `DELETE FROM short_links WHERE expires_at < now()`
+
+// DeleteExpiredShortLinks removes every link whose expiry has passed and
+// returns how many were removed.
+func (r *PostgresRepo) DeleteExpiredShortLinks(ctx context.Context) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "DeleteExpiredShortLinks", "DELETE", "short_links")
+	defer func() { endSpan(span, err) }()
+	res, err := r.db.ExecContext(ctx, deleteExpiredShortLinksSQL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired short links: %w", classify(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted short links: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,80 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/config"
+	"github.com/maojcn/shortlink/internal/migrate"
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+// newIntegrationRepo connects to the scratch Postgres database in
+// SHORTLINK_TEST_DSN and applies the migrations. Run with
+// "go test -tags integration ./internal/repository".
+func newIntegrationRepo(t *testing.T) *PostgresRepo {
+	t.Helper()
+	dsn := os.Getenv("SHORTLINK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("SHORTLINK_TEST_DSN is not set")
+	}
+	m, err := migrate.New(dsn)
+	if err != nil {
+		t.Fatalf("migrate.New: %v", err)
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	repo, err := NewPostgresRepo(config.DatabaseConfig{DSN: dsn, ConnectMaxAttempts: 1}, SerialSource{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewPostgresRepo: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestCreateShortLinkConcurrentCustomCodeIntegration(t *testing.T) {
+	repo := newIntegrationRepo(t)
+	code := fmt.Sprintf("race-%d", time.Now().UnixNano())
+
+	const callers = 8
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = repo.CreateShortLink(context.Background(), models.NewShortLink{
+				OriginalURL: "https://example.com",
+				CustomCode:  code,
+			})
+		}()
+	}
+	wg.Wait()
+
+	created, taken := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, ErrCodeTaken):
+			taken++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if created != 1 || taken != callers-1 {
+		t.Errorf("created %d, taken %d; want exactly one winner", created, taken)
+	}
+}
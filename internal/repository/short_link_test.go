@@ -0,0 +1,398 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/lib/pq/pqerror"
+
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+func newMockRepo(t *testing.T) (*PostgresRepo, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return &PostgresRepo{db: sqlx.NewDb(db, "postgres"), seq: SerialSource{}}, mock
+}
+
+var linkColumns = []string{"id", "code", "original_url", "user_id", "created_at", "expires_at"}
+
+var (
+	nextIDQuery     = regexp.QuoteMeta(nextShortLinkIDSQL)
+	insertLinkQuery = regexp.QuoteMeta(insertShortLinkSQL)
+	customLinkQuery = regexp.QuoteMeta(insertCustomShortLinkSQL)
+)
+
+func expectNextID(mock sqlmock.Sqlmock, id int64) {
+	mock.ExpectQuery(nextIDQuery).WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(id))
+}
+
+func TestCreateShortLink(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	created := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	expectNextID(mock, 62)
+	mock.ExpectQuery(insertLinkQuery).
+		WithArgs(int64(62), "10", "https://example.com", int64(1)<<40, nil, nil, "").
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(62), "10", "https://example.com", int64(1)<<40, created, nil))
+
+	link, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com", UserID: 1 << 40})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+	if link.ID != 62 || link.Code != "10" || link.UserID != 1<<40 || !link.CreatedAt.Equal(created) {
+		t.Errorf("link = %+v", link)
+	}
+	if link.ExpiresAt != nil {
+		t.Errorf("ExpiresAt = %v, want nil", link.ExpiresAt)
+	}
+}
+
+func TestCreateShortLinkWithExpiry(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expires := time.Date(2026, 10, 24, 12, 0, 0, 0, time.UTC)
+
+	expectNextID(mock, 1)
+	mock.ExpectQuery(insertLinkQuery).
+		WithArgs(int64(1), "1", "https://example.com", int64(0), &expires, nil, "").
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(1), "1", "https://example.com", int64(0), time.Now(), expires))
+
+	link, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com", ExpiresAt: &expires})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+	if link.ExpiresAt == nil || !link.ExpiresAt.Equal(expires) {
+		t.Errorf("ExpiresAt = %v, want %v", link.ExpiresAt, expires)
+	}
+}
+
+func TestCreateShortLinkWithDescription(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	expectNextID(mock, 1)
+	mock.ExpectQuery(insertLinkQuery).
+		WithArgs(int64(1), "1", "https://example.com", int64(0), nil, nil, "launch post").
+		WillReturnRows(sqlmock.NewRows(append(linkColumns, "description")).
+			AddRow(int64(1), "1", "https://example.com", int64(0), time.Now(), nil, "launch post"))
+
+	link, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com", Description: "launch post"})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+	if link.Description != "launch post" {
+		t.Errorf("Description = %q, want %q", link.Description, "launch post")
+	}
+}
+
+func TestCreateShortLinkWithCreatedAt(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	created := time.Date(2019, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	expectNextID(mock, 5)
+	mock.ExpectQuery(customLinkQuery).
+		WithArgs(int64(5), "bit-ly", "https://example.com", int64(0), nil, &created, "").
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(5), "bit-ly", "https://example.com", int64(0), created, nil))
+
+	link, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com", CustomCode: "bit-ly", CreatedAt: &created})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+	if !link.CreatedAt.Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", link.CreatedAt, created)
+	}
+}
+
+func TestCreateShortLinkSequenceError(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	dbErr := errors.New("connection refused")
+	mock.ExpectQuery(nextIDQuery).WillReturnError(dbErr)
+
+	if _, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com"}); !errors.Is(err, dbErr) {
+		t.Errorf("error = %v, want %v", err, dbErr)
+	}
+}
+
+func TestCreateShortLinkInsertError(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	dbErr := errors.New("connection refused")
+	expectNextID(mock, 1)
+	mock.ExpectQuery(insertLinkQuery).WillReturnError(dbErr)
+
+	if _, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com"}); !errors.Is(err, dbErr) {
+		t.Errorf("error = %v, want %v", err, dbErr)
+	}
+}
+
+func TestCreateShortLinkCustomCode(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expectNextID(mock, 5)
+	mock.ExpectQuery(customLinkQuery).
+		WithArgs(int64(5), "my-promo_1", "https://example.com", int64(0), nil, nil, "").
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(5), "my-promo_1", "https://example.com", int64(0), time.Now(), nil))
+
+	link, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com", CustomCode: "my-promo_1"})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+	if link.Code != "my-promo_1" {
+		t.Errorf("Code = %q, want %q", link.Code, "my-promo_1")
+	}
+}
+
+func TestCreateShortLinkCustomCodeTaken(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expectNextID(mock, 5)
+	mock.ExpectQuery(customLinkQuery).
+		WillReturnError(&pq.Error{Code: pqerror.UniqueViolation})
+
+	if _, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com", CustomCode: "promo"}); !errors.Is(err, ErrCodeTaken) {
+		t.Errorf("error = %v, want ErrCodeTaken", err)
+	}
+}
+
+func TestCreateShortLinkOwnerUsesReservedPrefix(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expectNextID(mock, 5)
+	mock.ExpectQuery(customLinkQuery).
+		WithArgs(int64(5), "acme-launch", "https://acme.example", int64(7), nil, nil, "").
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(5), "acme-launch", "https://acme.example", int64(7), time.Now(), nil))
+
+	link, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://acme.example", UserID: 7, CustomCode: "acme-launch"})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+	if link.Code != "acme-launch" {
+		t.Errorf("Code = %q, want %q", link.Code, "acme-launch")
+	}
+}
+
+func TestCreateShortLinkReservedPrefixBlocksOthers(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expectNextID(mock, 5)
+	// The guarded INSERT .. SELECT returns no row when the prefix belongs
+	// to another user.
+	mock.ExpectQuery(customLinkQuery).
+		WithArgs(int64(5), "acme-launch", "https://evil.example", int64(8), nil, nil, "").
+		WillReturnRows(sqlmock.NewRows(linkColumns))
+
+	_, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://evil.example", UserID: 8, CustomCode: "acme-launch"})
+	if !errors.Is(err, ErrPrefixReserved) {
+		t.Errorf("error = %v, want ErrPrefixReserved", err)
+	}
+}
+
+func TestCreateShortLinkSkipsIDTakenByCustomCode(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expectNextID(mock, 62)
+	mock.ExpectQuery(insertLinkQuery).
+		WithArgs(int64(62), "10", "https://example.com", int64(0), nil, nil, "").
+		WillReturnError(&pq.Error{Code: pqerror.UniqueViolation})
+	expectNextID(mock, 63)
+	mock.ExpectQuery(insertLinkQuery).
+		WithArgs(int64(63), "11", "https://example.com", int64(0), nil, nil, "").
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(63), "11", "https://example.com", int64(0), time.Now(), nil))
+
+	link, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+	if link.Code != "11" {
+		t.Errorf("Code = %q, want %q", link.Code, "11")
+	}
+}
+
+func TestCreateShortLinkGivesUpAfterRepeatedCollisions(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	for i := int64(1); i <= maxCodeAttempts; i++ {
+		expectNextID(mock, i)
+		mock.ExpectQuery(insertLinkQuery).
+			WillReturnError(&pq.Error{Code: pqerror.UniqueViolation})
+	}
+
+	_, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com"})
+	if !isUniqueViolation(err) || errors.Is(err, ErrCodeTaken) {
+		t.Errorf("error = %v, want wrapped unique violation", err)
+	}
+}
+
+func TestGetShortLinkByCode(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(regexp.QuoteMeta(getShortLinkByCodeSQL)).
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(3), "abc", "https://example.com", int64(7), time.Now(), nil))
+
+	link, err := repo.GetShortLinkByCode(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("GetShortLinkByCode: %v", err)
+	}
+	if link.ID != 3 || link.OriginalURL != "https://example.com" || link.UserID != 7 {
+		t.Errorf("link = %+v", link)
+	}
+}
+
+func TestGetShortLinkByCodeMissing(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(regexp.QuoteMeta(getShortLinkByCodeSQL)).
+		WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows(linkColumns))
+
+	_, err := repo.GetShortLinkByCode(context.Background(), "nope")
+	if !errors.Is(err, ErrLinkNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("error = %v, want ErrLinkNotFound wrapping sql.ErrNoRows", err)
+	}
+}
+
+func TestGetShortLinkByCodeDBError(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	dbErr := errors.New("connection reset")
+	mock.ExpectQuery(regexp.QuoteMeta(getShortLinkByCodeSQL)).WillReturnError(dbErr)
+
+	_, err := repo.GetShortLinkByCode(context.Background(), "abc")
+	if !errors.Is(err, dbErr) || errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("error = %v, want the database error and not ErrLinkNotFound", err)
+	}
+}
+
+func TestListUserShortLinks(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(listUserShortLinksSQL)).
+		WithArgs(int64(7), pq.Array([]string{"a", "b"}), 100).
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(2), "b", "https://b.example", int64(7), now, nil).
+			AddRow(int64(1), "a", "https://a.example", int64(7), now, nil))
+
+	links, err := repo.ListUserShortLinks(context.Background(), 7, []string{"a", "b"}, 100)
+	if err != nil {
+		t.Fatalf("ListUserShortLinks: %v", err)
+	}
+	if len(links) != 2 || links[0].Code != "b" || links[1].Code != "a" {
+		t.Errorf("links = %+v", links)
+	}
+}
+
+func TestListUserShortLinksAll(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(regexp.QuoteMeta(listUserShortLinksSQL)).
+		WithArgs(int64(7), pq.Array([]string(nil)), 10).
+		WillReturnRows(sqlmock.NewRows(linkColumns))
+
+	links, err := repo.ListUserShortLinks(context.Background(), 7, nil, 10)
+	if err != nil {
+		t.Fatalf("ListUserShortLinks: %v", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("links = %+v, want none", links)
+	}
+}
+
+func TestDeleteExpiredShortLinks(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectExec(regexp.QuoteMeta(deleteExpiredShortLinksSQL)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := repo.DeleteExpiredShortLinks(context.Background())
+	if err != nil {
+		t.Fatalf("DeleteExpiredShortLinks: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("deleted %d, want 3", n)
+	}
+}
+
+var (
+	savepointExec  = regexp.QuoteMeta("SAVEPOINT short_link")
+	releaseExec    = regexp.QuoteMeta("RELEASE SAVEPOINT short_link")
+	rollbackToExec = regexp.QuoteMeta("ROLLBACK TO SAVEPOINT short_link")
+)
+
+func TestBatchCreateShortLinks(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	// Item 0: generated code.
+	expectNextID(mock, 62)
+	mock.ExpectExec(savepointExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insertLinkQuery).
+		WithArgs(int64(62), "10", "https://a.example", int64(7), nil, nil, "").
+		WillReturnRows(sqlmock.NewRows(linkColumns).AddRow(int64(62), "10", "https://a.example", int64(7), now, nil))
+	mock.ExpectExec(releaseExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	// Item 1: custom code already taken; only the savepoint is rolled back.
+	expectNextID(mock, 63)
+	mock.ExpectExec(savepointExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(customLinkQuery).WillReturnError(&pq.Error{Code: pqerror.UniqueViolation})
+	mock.ExpectExec(rollbackToExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	// Item 2: custom code in someone else's reserved prefix.
+	expectNextID(mock, 64)
+	mock.ExpectExec(savepointExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(customLinkQuery).WillReturnRows(sqlmock.NewRows(linkColumns))
+	mock.ExpectExec(releaseExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	results, err := repo.BatchCreateShortLinks(context.Background(), []models.NewShortLink{
+		{OriginalURL: "https://a.example", UserID: 7},
+		{OriginalURL: "https://b.example", UserID: 7, CustomCode: "taken"},
+		{OriginalURL: "https://c.example", UserID: 7, CustomCode: "acme-x"},
+	})
+	if err != nil {
+		t.Fatalf("BatchCreateShortLinks: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].Link == nil || results[0].Link.Code != "10" || results[0].Err != nil {
+		t.Errorf("result 0 = %+v", results[0])
+	}
+	if results[1].Link != nil || !errors.Is(results[1].Err, ErrCodeTaken) {
+		t.Errorf("result 1 = %+v, want ErrCodeTaken", results[1])
+	}
+	if results[2].Link != nil || !errors.Is(results[2].Err, ErrPrefixReserved) {
+		t.Errorf("result 2 = %+v, want ErrPrefixReserved", results[2])
+	}
+}
+
+func TestBatchCreateShortLinksRollsBackOnDatabaseError(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	dbErr := errors.New("connection reset")
+
+	mock.ExpectBegin()
+	expectNextID(mock, 1)
+	mock.ExpectExec(savepointExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insertLinkQuery).WillReturnRows(sqlmock.NewRows(linkColumns).
+		AddRow(int64(1), "1", "https://a.example", int64(0), time.Now(), nil))
+	mock.ExpectExec(releaseExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(nextIDQuery).WillReturnError(dbErr)
+	mock.ExpectRollback()
+
+	_, err := repo.BatchCreateShortLinks(context.Background(), []models.NewShortLink{
+		{OriginalURL: "https://a.example"},
+		{OriginalURL: "https://b.example"},
+	})
+	if !errors.Is(err, dbErr) {
+		t.Errorf("error = %v, want %v", err, dbErr)
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/maojcn/shortlink/internal/servertiming"
+)
+
+// tracerName identifies this package's spans.
+const tracerName = "github.com/maojcn/shortlink/internal/repository"
+
+// querySpan is a repository call in progress: its trace span and, when
+// the request is timed for Server-Timing, when it started.
+type querySpan struct {
+	trace.Span
+	timings *servertiming.Timings
+	start   time.Time
+}
+
+type inQueryKey struct{}
+
+// startSpan starts a client span named "PostgresRepo.<method>" for a query
+// performing operation on table. The tracer is looked up on each call so
+// the global provider installed by tracing.Setup is used; until then it is
+// a no-op. If ctx carries servertiming.Timings the call's duration is
+// added to them by endSpan; calls made within another call, such as the
+// inserts of a batch, are part of its duration and not added again.
+func startSpan(ctx context.Context, method, operation, table string) (context.Context, *querySpan) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "PostgresRepo."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.collection.name", table),
+			attribute.String("db.query.summary", operation+" "+table),
+		))
+	qs := &querySpan{Span: span}
+	if t := servertiming.FromContext(ctx); t != nil && ctx.Value(inQueryKey{}) == nil {
+		qs.timings, qs.start = t, time.Now()
+		ctx = context.WithValue(ctx, inQueryKey{}, true)
+	}
+	return ctx, qs
+}
+
+// endSpan ends span, marking it failed if err is a database failure.
+// Outcomes callers expect, such as a taken code or a missing row, are not
+// failures.
+func endSpan(span *querySpan, err error) {
+	if span.timings != nil {
+		span.timings.AddQuery(time.Since(span.start))
+	}
+	if err != nil && !isExpected(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func isExpected(err error) bool {
+	for _, target := range []error{ErrCodeTaken, ErrPrefixReserved, ErrAPIKeyNotFound, ErrLinkNotFound, ErrShareTokenNotFound, sql.ErrNoRows} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
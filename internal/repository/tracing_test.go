@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/servertiming"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestRepositorySpans(t *testing.T) {
+	rec := recordSpans(t)
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(regexp.QuoteMeta(getShortLinkByCodeSQL)).
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(3), "abc", "https://example.com", int64(7), time.Now(), nil))
+	mock.ExpectQuery(regexp.QuoteMeta(getShortLinkByCodeSQL)).
+		WillReturnRows(sqlmock.NewRows(linkColumns))
+	mock.ExpectExec(regexp.QuoteMeta(deleteExpiredShortLinksSQL)).
+		WillReturnError(errors.New("connection reset"))
+
+	ctx := context.Background()
+	_, _ = repo.GetShortLinkByCode(ctx, "abc")
+	_, _ = repo.GetShortLinkByCode(ctx, "missing")
+	_, _ = repo.DeleteExpiredShortLinks(ctx)
+
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	wantStatus := []codes.Code{codes.Unset, codes.Unset, codes.Error}
+	for i, name := range []string{"PostgresRepo.GetShortLinkByCode", "PostgresRepo.GetShortLinkByCode", "PostgresRepo.DeleteExpiredShortLinks"} {
+		if spans[i].Name() != name {
+			t.Errorf("span %d is %q, want %q", i, spans[i].Name(), name)
+		}
+		if got := spans[i].Status().Code; got != wantStatus[i] {
+			t.Errorf("span %d status = %v, want %v", i, got, wantStatus[i])
+		}
+	}
+	attrs := attribute.NewSet(spans[0].Attributes()...)
+	if v, _ := attrs.Value("db.query.summary"); v.AsString() != "SELECT short_links" {
+		t.Errorf("db.query.summary = %q", v.AsString())
+	}
+}
+
+func TestRepositoryCallsAreTimed(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(regexp.QuoteMeta(getShortLinkByCodeSQL)).
+		WillReturnRows(sqlmock.NewRows(linkColumns))
+	mock.ExpectBegin()
+	expectNextID(mock, 1)
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insertLinkQuery).WillReturnRows(sqlmock.NewRows(linkColumns).
+		AddRow(int64(1), "1", "https://example.com", int64(0), time.Now(), nil))
+	mock.ExpectExec("RELEASE SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	ctx, timings := servertiming.NewContext(context.Background())
+	_, _ = repo.GetShortLinkByCode(ctx, "abc")
+	if _, err := repo.BatchCreateShortLinks(ctx, []models.NewShortLink{{OriginalURL: "https://example.com"}}); err != nil {
+		t.Fatalf("BatchCreateShortLinks: %v", err)
+	}
+
+	// The batch's inner CreateShortLinkTx is part of the batch's time.
+	if !strings.Contains(timings.Header(time.Now()), `desc="queries: 2"`) {
+		t.Errorf("Server-Timing = %q, want 2 queries", timings.Header(time.Now()))
+	}
+}
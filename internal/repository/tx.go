@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WithTx runs fn in a transaction. The transaction is committed if fn
+// returns nil and rolled back if fn returns an error or panics; a panic is
+// re-raised after the rollback.
+func (r *PostgresRepo) WithTx(ctx context.Context, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classify(err))
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rbErr))
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", classify(err))
+	}
+	return nil
+}
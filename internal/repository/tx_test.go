@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+func TestWithTxCommits(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectBegin()
+	expectNextID(mock, 1)
+	mock.ExpectExec(savepointExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insertLinkQuery).WillReturnRows(sqlmock.NewRows(linkColumns).
+		AddRow(int64(1), "1", "https://example.com", int64(0), time.Now(), nil))
+	mock.ExpectExec(releaseExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.WithTx(context.Background(), func(tx *sqlx.Tx) error {
+		_, err := repo.CreateShortLinkTx(context.Background(), tx, models.NewShortLink{OriginalURL: "https://example.com"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectBegin()
+	expectNextID(mock, 1)
+	mock.ExpectExec(savepointExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insertLinkQuery).WillReturnRows(sqlmock.NewRows(linkColumns).
+		AddRow(int64(1), "1", "https://example.com", int64(0), time.Now(), nil))
+	mock.ExpectExec(releaseExec).WillReturnResult(sqlmock.NewResult(0, 0))
+	// No commit: the inserted row must be rolled back.
+	mock.ExpectRollback()
+
+	fnErr := errors.New("second step failed")
+	err := repo.WithTx(context.Background(), func(tx *sqlx.Tx) error {
+		if _, err := repo.CreateShortLinkTx(context.Background(), tx, models.NewShortLink{OriginalURL: "https://example.com"}); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("error = %v, want %v", err, fnErr)
+	}
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the original panic", p)
+		}
+	}()
+	_ = repo.WithTx(context.Background(), func(*sqlx.Tx) error { panic("boom") })
+}
+
+func TestWithTxBeginError(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	dbErr := errors.New("too many connections")
+	mock.ExpectBegin().WillReturnError(dbErr)
+
+	called := false
+	err := repo.WithTx(context.Background(), func(*sqlx.Tx) error { called = true; return nil })
+	if !errors.Is(err, dbErr) || called {
+		t.Errorf("err = %v, called = %v; want begin error and no call", err, called)
+	}
+}
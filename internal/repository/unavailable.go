@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/lib/pq"
+	"github.com/lib/pq/pqerror"
+)
+
+// ErrUnavailable wraps errors caused by losing the connection to Postgres
+// rather than by the query, such as a reset connection or a server
+// shutting down. Handlers answer it with 503 instead of 500, since the
+// same request may well succeed once the database is back.
+var ErrUnavailable = errors.New("database unavailable")
+
+// isConnectionError reports whether err means the connection failed, as
+// opposed to the statement. Context cancellation is not a connection
+// error even though it closes the connection.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqerror.AdminShutdown, pqerror.CrashShutdown, pqerror.CannotConnectNow:
+			return true
+		}
+		return pqErr.Code.Class() == pqerror.ClassConnectionException
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr)
+}
+
+// classify wraps err in ErrUnavailable if it is a connection error, and
+// returns it unchanged otherwise.
+func classify(err error) error {
+	if isConnectionError(err) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
+
+// retryRead runs read, and runs it once more if it failed with a
+// connection error: the pool then hands out another connection, and a read
+// is safe to repeat. database/sql already retries driver.ErrBadConn when
+// the statement never reached the server; this also covers a connection
+// lost mid-query. Writes are never retried, as the first attempt may have
+// been applied.
+func retryRead(ctx context.Context, read func() error) error {
+	err := read()
+	if isConnectionError(err) && ctx.Err() == nil {
+		err = read()
+	}
+	return err
+}
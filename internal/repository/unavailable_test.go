@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/lib/pq/pqerror"
+
+	"github.com/maojcn/shortlink/internal/models"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"bad conn", driver.ErrBadConn, true},
+		{"wrapped bad conn", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"eof mid-query", io.ErrUnexpectedEOF, true},
+		{"connection reset", reset, true},
+		{"broken pipe", syscall.EPIPE, true},
+		{"admin shutdown", &pq.Error{Code: pqerror.AdminShutdown}, true},
+		{"connection exception class", &pq.Error{Code: pqerror.ConnectionException}, true},
+		{"unique violation", &pq.Error{Code: pqerror.UniqueViolation}, false},
+		{"syntax error", &pq.Error{Code: pqerror.SyntaxError}, false},
+		{"cancelled", context.Canceled, false},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+		{"no rows", ErrLinkNotFound, false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isConnectionError(tt.err); got != tt.want {
+			t.Errorf("%s: isConnectionError(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGetShortLinkByCodeRetriesLostConnection(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	query := regexp.QuoteMeta(getShortLinkByCodeSQL)
+	mock.ExpectQuery(query).WithArgs("abc").WillReturnError(io.ErrUnexpectedEOF)
+	mock.ExpectQuery(query).WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows(linkColumns).
+			AddRow(int64(3), "abc", "https://example.com", int64(7), time.Now(), nil))
+
+	link, err := repo.GetShortLinkByCode(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("GetShortLinkByCode: %v", err)
+	}
+	if link.Code != "abc" {
+		t.Errorf("link = %+v", link)
+	}
+}
+
+func TestReadsRetryOnceThenFailUnavailable(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	tests := []struct {
+		name  string
+		query string
+		read  func(*PostgresRepo) error
+	}{
+		{"GetShortLinkByCode", getShortLinkByCodeSQL, func(r *PostgresRepo) error {
+			_, err := r.GetShortLinkByCode(context.Background(), "abc")
+			return err
+		}},
+		{"ListUserShortLinks", listUserShortLinksSQL, func(r *PostgresRepo) error {
+			_, err := r.ListUserShortLinks(context.Background(), 7, nil, 10)
+			return err
+		}},
+		{"UserIDForAPIKey", userIDForAPIKeySQL, func(r *PostgresRepo) error {
+			_, err := r.UserIDForAPIKey(context.Background(), "key")
+			return err
+		}},
+		{"UserIDForShareToken", userIDForShareTokenSQL, func(r *PostgresRepo) error {
+			_, err := r.UserIDForShareToken(context.Background(), "st_x", models.ShareScopeFeed)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepo(t)
+			// Exactly two attempts: a third query would not match any
+			// expectation and fail differently.
+			mock.ExpectQuery(regexp.QuoteMeta(tt.query)).WillReturnError(reset)
+			mock.ExpectQuery(regexp.QuoteMeta(tt.query)).WillReturnError(reset)
+
+			err := tt.read(repo)
+			if !errors.Is(err, ErrUnavailable) || !errors.Is(err, syscall.ECONNRESET) {
+				t.Errorf("error = %v, want ErrUnavailable wrapping the reset", err)
+			}
+		})
+	}
+}
+
+func TestQueryErrorsAreNotRetried(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(regexp.QuoteMeta(getShortLinkByCodeSQL)).
+		WillReturnError(&pq.Error{Code: pqerror.UndefinedColumn})
+
+	_, err := repo.GetShortLinkByCode(context.Background(), "abc")
+	if err == nil || errors.Is(err, ErrUnavailable) {
+		t.Errorf("error = %v, want a plain query error", err)
+	}
+}
+
+func TestWritesAreNotRetried(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	expectNextID(mock, 1)
+	mock.ExpectQuery(insertLinkQuery).WillReturnError(io.ErrUnexpectedEOF)
+
+	_, err := repo.CreateShortLink(context.Background(), models.NewShortLink{OriginalURL: "https://example.com"})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("error = %v, want ErrUnavailable", err)
+	}
+}
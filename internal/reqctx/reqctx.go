@@ -0,0 +1,62 @@
+// Package reqctx carries the request ID, authenticated user and tenant
+// through a request's context.Context. The middleware set them; handlers,
+// quotas and the loggers read them.
+package reqctx
+
+import "context"
+
+// User is the authenticated identity attached to a request.
+type User struct {
+	ID   int64
+	Role string
+}
+
+// Tenant is the tenant a request is scoped to.
+type Tenant struct {
+	ID   int64
+	Slug string
+}
+
+// Unexported key types make collisions with other packages' context values
+// impossible.
+type (
+	requestIDKey struct{}
+	userKey      struct{}
+	tenantKey    struct{}
+)
+
+// WithRequestID returns a copy of ctx carrying the request ID id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID stored by WithRequestID, or "" if
+// there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithUser returns a copy of ctx carrying u.
+func WithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// UserFromContext returns the user stored by WithUser. The boolean is false
+// when the request is unauthenticated.
+func UserFromContext(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(userKey{}).(User)
+	return u, ok
+}
+
+// WithTenant returns a copy of ctx carrying t.
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// TenantFromContext returns the tenant stored by WithTenant. The boolean is
+// false when no tenant has been resolved for the request.
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(tenantKey{}).(Tenant)
+	return t, ok
+}
@@ -0,0 +1,65 @@
+package reqctx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserRoundTrip(t *testing.T) {
+	want := User{ID: 42, Role: "admin"}
+	got, ok := UserFromContext(WithUser(context.Background(), want))
+	if !ok {
+		t.Fatal("UserFromContext: no user found")
+	}
+	if got != want {
+		t.Errorf("UserFromContext = %+v, want %+v", got, want)
+	}
+}
+
+func TestTenantRoundTrip(t *testing.T) {
+	want := Tenant{ID: 7, Slug: "acme"}
+	got, ok := TenantFromContext(WithTenant(context.Background(), want))
+	if !ok {
+		t.Fatal("TenantFromContext: no tenant found")
+	}
+	if got != want {
+		t.Errorf("TenantFromContext = %+v, want %+v", got, want)
+	}
+}
+
+func TestUserAndTenantAreIndependent(t *testing.T) {
+	ctx := WithTenant(WithUser(context.Background(), User{ID: 1}), Tenant{ID: 2})
+	if u, _ := UserFromContext(ctx); u.ID != 1 {
+		t.Errorf("user ID = %d, want 1", u.ID)
+	}
+	if tn, _ := TenantFromContext(ctx); tn.ID != 2 {
+		t.Errorf("tenant ID = %d, want 2", tn.ID)
+	}
+}
+
+func TestRequestIDRoundTrip(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1")
+	if got := RequestIDFromContext(ctx); got != "req-1" {
+		t.Errorf("RequestIDFromContext = %q, want %q", got, "req-1")
+	}
+}
+
+func TestMissingValues(t *testing.T) {
+	ctx := context.Background()
+	if id := RequestIDFromContext(ctx); id != "" {
+		t.Errorf("RequestIDFromContext = %q, want empty", id)
+	}
+	if u, ok := UserFromContext(ctx); ok || u != (User{}) {
+		t.Errorf("UserFromContext = %+v, %v; want zero value, false", u, ok)
+	}
+	if tn, ok := TenantFromContext(ctx); ok || tn != (Tenant{}) {
+		t.Errorf("TenantFromContext = %+v, %v; want zero value, false", tn, ok)
+	}
+}
+
+func TestStringKeysDoNotCollide(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "user", User{ID: 99})
+	if _, ok := UserFromContext(ctx); ok {
+		t.Error("UserFromContext matched a value stored under a string key")
+	}
+}
@@ -0,0 +1,103 @@
+// Package reservedcodes holds the operator's list of short codes that can
+// never be chosen as custom codes, loaded from a file.
+package reservedcodes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Set is a reloadable set of reserved codes. Codes match
+// case-insensitively, so reserving "brand" also blocks "Brand". A nil Set
+// reserves nothing. It is safe for concurrent use.
+type Set struct {
+	path string
+
+	mu    sync.RWMutex
+	codes map[string]struct{}
+}
+
+// Load reads the reserved codes in path: one code per line, with blank
+// lines and lines starting with '#' ignored. An empty path gives an empty
+// Set.
+func Load(path string) (*Set, error) {
+	s := &Set{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Contains reports whether code is reserved.
+func (s *Set) Contains(code string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.codes[strings.ToLower(code)]
+	return ok
+}
+
+// Len returns the number of reserved codes.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.codes)
+}
+
+// Reload re-reads the file. If it cannot be read the current codes are
+// kept.
+func (s *Set) Reload() error {
+	codes := make(map[string]struct{})
+	if s.path != "" {
+		f, err := os.Open(s.path)
+		if err != nil {
+			return fmt.Errorf("failed to read reserved codes: %w", err)
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			codes[strings.ToLower(line)] = struct{}{}
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("failed to read reserved codes: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.codes = codes
+	s.mu.Unlock()
+	return nil
+}
+
+// ReloadOn reloads the set each time a value arrives on signals, typically
+// SIGHUP from signal.Notify, until ctx is cancelled. Failed reloads are
+// logged and leave the current codes in place.
+func (s *Set) ReloadOn(ctx context.Context, signals <-chan os.Signal, logger *zap.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := s.Reload(); err != nil {
+				logger.Error("reserved codes reload failed", zap.Error(err))
+				continue
+			}
+			logger.Info("reloaded reserved codes", zap.Int("count", s.Len()))
+		}
+	}
+}
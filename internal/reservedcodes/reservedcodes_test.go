@@ -0,0 +1,100 @@
+package reservedcodes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reserved.txt")
+	writeFile(t, path, "# brand terms\nacme\n\n  Login  \n")
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for code, want := range map[string]bool{"acme": true, "ACME": true, "login": true, "other": false, "# brand terms": false} {
+		if got := s.Contains(code); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", code, got, want)
+		}
+	}
+	if s.Len() != 2 {
+		t.Errorf("Len = %d, want 2", s.Len())
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	s, err := Load("")
+	if err != nil || s.Contains("acme") {
+		t.Errorf("Load(\"\") = %v, %v; want an empty set", s, err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+	var nilSet *Set
+	if nilSet.Contains("acme") || nilSet.Len() != 0 {
+		t.Error("nil Set reserves codes")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reserved.txt")
+	writeFile(t, path, "acme\n")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	writeFile(t, path, "acme\nnewbrand\n")
+	if err := s.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !s.Contains("newbrand") {
+		t.Error("Reload did not pick up a new code")
+	}
+
+	os.Remove(path)
+	if err := s.Reload(); err == nil {
+		t.Error("Reload of a removed file succeeded")
+	}
+	if !s.Contains("acme") || !s.Contains("newbrand") {
+		t.Error("failed Reload dropped the current codes")
+	}
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reserved.txt")
+	writeFile(t, path, "acme\n")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal)
+	go s.ReloadOn(ctx, signals, zap.NewNop())
+
+	writeFile(t, path, "acme\nsale\n")
+	signals <- syscall.SIGHUP
+	deadline := time.Now().Add(time.Second)
+	for !s.Contains("sale") {
+		if time.Now().After(deadline) {
+			t.Fatal("SIGHUP did not reload the codes")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
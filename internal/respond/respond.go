@@ -0,0 +1,82 @@
+// Package respond writes the service's JSON responses, so that every
+// handler and middleware formats them the same way.
+package respond
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCharset is the charset parameter used when Options leaves it
+// empty.
+const defaultCharset = "utf-8"
+
+// optionsKey is the gin context key Use stores the Options under.
+const optionsKey = "respond.options"
+
+// Options controls how responses are encoded. AllowPretty lets clients ask
+// for indented output with ?pretty=true. Charset is the charset parameter
+// of the Content-Type, "utf-8" when empty; the body itself is always the
+// UTF-8 that encoding/json produces.
+type Options struct {
+	AllowPretty bool
+	Charset     string
+}
+
+// Use returns a middleware that makes JSON and AbortJSON apply opts to the
+// rest of the chain. Without it responses use the zero Options.
+func Use(opts Options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(optionsKey, opts)
+		c.Next()
+	}
+}
+
+// JSON writes body as the JSON response with status. The Content-Type is
+// always set explicitly, replacing any set earlier in the chain. The
+// output is indented when the request has ?pretty=true and the Options
+// allow it, and compact otherwise. A body that cannot be encoded is
+// recorded on c and answered with a bare 500.
+func JSON(c *gin.Context, status int, body any) {
+	contentType, data, err := Encode(c, body)
+	if err != nil {
+		_ = c.Error(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Content-Type", contentType)
+	c.Data(status, contentType, data)
+}
+
+// Encode returns the Content-Type and bytes JSON would write for body on
+// c's request, for middleware that must write a response outside the
+// handler chain.
+func Encode(c *gin.Context, body any) (contentType string, data []byte, err error) {
+	opts, _ := c.Value(optionsKey).(Options)
+	if opts.AllowPretty && wantsPretty(c) {
+		data, err = json.MarshalIndent(body, "", "    ")
+	} else {
+		data, err = json.Marshal(body)
+	}
+	charset := opts.Charset
+	if charset == "" {
+		charset = defaultCharset
+	}
+	return "application/json; charset=" + charset, data, err
+}
+
+// AbortJSON is JSON for middleware: it also stops the rest of the chain.
+func AbortJSON(c *gin.Context, status int, body any) {
+	c.Abort()
+	JSON(c, status, body)
+}
+
+// wantsPretty reports whether the pretty query parameter is a true boolean
+// such as "true" or "1".
+func wantsPretty(c *gin.Context) bool {
+	pretty, err := strconv.ParseBool(c.Query("pretty"))
+	return err == nil && pretty
+}
@@ -0,0 +1,89 @@
+package respond
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter(opts *Options) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if opts != nil {
+		r.Use(Use(*opts))
+	}
+	r.GET("/", func(c *gin.Context) {
+		JSON(c, http.StatusOK, gin.H{"a": 1})
+	})
+	r.GET("/abort", func(c *gin.Context) {
+		AbortJSON(c, http.StatusTeapot, gin.H{"a": 1})
+	})
+	return r
+}
+
+func TestJSON(t *testing.T) {
+	const (
+		compact = `{"a":1}`
+		pretty  = "{\n    \"a\": 1\n}"
+	)
+	tests := []struct {
+		name string
+		opts *Options
+		url  string
+		want string
+	}{
+		{"default", nil, "/", compact},
+		{"pretty not allowed", &Options{}, "/?pretty=true", compact},
+		{"allowed but not asked", &Options{AllowPretty: true}, "/", compact},
+		{"pretty", &Options{AllowPretty: true}, "/?pretty=true", pretty},
+		{"pretty=1", &Options{AllowPretty: true}, "/?pretty=1", pretty},
+		{"pretty=false", &Options{AllowPretty: true}, "/?pretty=false", compact},
+		{"pretty=junk", &Options{AllowPretty: true}, "/?pretty=junk", compact},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newRouter(tt.opts).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+		})
+	}
+}
+
+func TestAbortJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	newRouter(&Options{AllowPretty: true}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abort?pretty=true", nil))
+	if w.Code != http.StatusTeapot || w.Body.String() != "{\n    \"a\": 1\n}" {
+		t.Errorf("got %d %q", w.Code, w.Body)
+	}
+}
+
+func TestJSONCharset(t *testing.T) {
+	for charset, want := range map[string]string{
+		"":           "application/json; charset=utf-8",
+		"UTF-8":      "application/json; charset=UTF-8",
+		"iso-8859-1": "application/json; charset=iso-8859-1",
+	} {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(Use(Options{Charset: charset}))
+		r.GET("/", func(c *gin.Context) {
+			c.Header("Content-Type", "text/plain")
+			JSON(c, http.StatusOK, gin.H{"name": "münchen"})
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := w.Header().Get("Content-Type"); got != want {
+			t.Errorf("Charset %q: Content-Type = %q, want %q", charset, got, want)
+		}
+		if w.Body.String() != `{"name":"münchen"}` {
+			t.Errorf("Charset %q: body = %q", charset, w.Body)
+		}
+	}
+}
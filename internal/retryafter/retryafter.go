@@ -0,0 +1,60 @@
+// Package retryafter formats the Retry-After header for 429 and 503
+// responses.
+package retryafter
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Format selects which of the two RFC 9110 forms of Retry-After to emit.
+type Format int
+
+const (
+	// Seconds emits a non-negative integer delay, e.g. "Retry-After: 30".
+	// It is the default because every client understands it.
+	Seconds Format = iota
+	// HTTPDate emits an IMF-fixdate, e.g.
+	// "Retry-After: Sun, 18 Oct 2026 10:00:00 GMT".
+	HTTPDate
+)
+
+// ParseFormat maps a config value ("seconds" or "http-date") to a Format.
+// An empty value selects Seconds.
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "", "seconds":
+		return Seconds, nil
+	case "http-date":
+		return HTTPDate, nil
+	default:
+		return 0, fmt.Errorf("retryafter: unknown format %q", s)
+	}
+}
+
+// Value returns the Retry-After value for a window that resets at reset, as
+// seen at now. Both forms are rounded up to the next whole second so that a
+// client honoring the header never retries before the window has reset.
+func Value(reset, now time.Time, f Format) string {
+	wait := reset.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+
+	if f == HTTPDate {
+		at := now.Add(wait).UTC()
+		if t := at.Truncate(time.Second); t.Before(at) {
+			at = t.Add(time.Second)
+		}
+		return at.Format(http.TimeFormat)
+	}
+	return strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10)
+}
+
+// Set writes the Retry-After header computed by Value.
+func Set(h http.Header, reset, now time.Time, f Format) {
+	h.Set("Retry-After", Value(reset, now, f))
+}
@@ -0,0 +1,89 @@
+package retryafter
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 10, 17, 12, 0, 0, 250*int(time.Millisecond), time.UTC)
+
+func TestValueSeconds(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset time.Time
+		want  string
+	}{
+		{"whole seconds", now.Add(30 * time.Second), "30"},
+		{"rounds up", now.Add(29*time.Second + time.Millisecond), "30"},
+		{"already reset", now.Add(-time.Second), "0"},
+		{"reset now", now, "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Value(tt.reset, now, Seconds)
+			if got != tt.want {
+				t.Errorf("Value = %q, want %q", got, tt.want)
+			}
+			if n, err := strconv.Atoi(got); err != nil || n < 0 {
+				t.Errorf("Value = %q is not a non-negative integer", got)
+			}
+		})
+	}
+}
+
+func TestValueHTTPDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset time.Time
+		want  time.Time
+	}{
+		{"rounds up", now.Add(30 * time.Second), time.Date(2026, 10, 17, 12, 0, 31, 0, time.UTC)},
+		{"already reset", now.Add(-time.Minute), time.Date(2026, 10, 17, 12, 0, 1, 0, time.UTC)},
+		{"non-UTC reset", now.Add(time.Hour).In(time.FixedZone("CEST", 2*60*60)), time.Date(2026, 10, 17, 13, 0, 1, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Value(tt.reset, now, HTTPDate)
+			parsed, err := time.Parse(http.TimeFormat, got)
+			if err != nil {
+				t.Fatalf("Value = %q is not an IMF-fixdate: %v", got, err)
+			}
+			if !parsed.Equal(tt.want) {
+				t.Errorf("Value = %q, want %q", got, tt.want.Format(http.TimeFormat))
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	h := http.Header{}
+	Set(h, now.Add(5*time.Second), now, Seconds)
+	if got := h.Get("Retry-After"); got != "5" {
+		t.Errorf("Retry-After = %q, want %q", got, "5")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", Seconds, false},
+		{"seconds", Seconds, false},
+		{"http-date", HTTPDate, false},
+		{"date", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFormat(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package selftest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/config"
+	"github.com/maojcn/shortlink/internal/migrate"
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+)
+
+// selftestURL is the destination of the link the self-test creates.
+const selftestURL = "https://example.com/shortlink-selftest"
+
+// Postgres returns the steps that check the database of cfg: connect,
+// create a scratch schema, apply the migrations to it, then create,
+// resolve and delete an already expired link through the repository, the
+// way the expiry sweeper does. Everything happens in the scratch schema,
+// which the cleanups drop, so the service's own tables are never touched.
+func Postgres(cfg *config.Config, logger *zap.Logger) []Step {
+	c := &postgresCheck{cfg: cfg, logger: logger}
+	return []Step{
+		{Name: "connect to database", Run: c.connect, Cleanup: c.disconnect},
+		{Name: "create scratch schema", Run: c.createSchema, Cleanup: c.dropSchema},
+		{Name: "migrate", Run: c.migrate},
+		{Name: "open repository", Run: c.openRepo, Cleanup: c.closeRepo},
+		{Name: "create link", Run: c.createLink},
+		{Name: "resolve link", Run: c.resolveLink},
+		{Name: "delete expired link", Run: c.deleteLink},
+	}
+}
+
+// postgresCheck carries state from one step to the next.
+type postgresCheck struct {
+	cfg    *config.Config
+	logger *zap.Logger
+
+	admin  *sqlx.DB
+	schema string
+	repo   *repository.PostgresRepo
+	link   *models.ShortLink
+}
+
+func (c *postgresCheck) connect(ctx context.Context) error {
+	db, err := sqlx.ConnectContext(ctx, "postgres", c.cfg.Database.DSN)
+	if err != nil {
+		return err
+	}
+	c.admin = db
+	return nil
+}
+
+func (c *postgresCheck) disconnect(context.Context) error {
+	return c.admin.Close()
+}
+
+func (c *postgresCheck) createSchema(ctx context.Context) error {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	schema := "shortlink_selftest_" + hex.EncodeToString(suffix)
+	if _, err := c.admin.ExecContext(ctx, "CREATE SCHEMA "+pq.QuoteIdentifier(schema)); err != nil {
+		return err
+	}
+	c.schema = schema
+	return nil
+}
+
+func (c *postgresCheck) dropSchema(ctx context.Context) error {
+	_, err := c.admin.ExecContext(ctx, "DROP SCHEMA "+pq.QuoteIdentifier(c.schema)+" CASCADE")
+	return err
+}
+
+func (c *postgresCheck) migrate(context.Context) error {
+	dsn, err := scopedDSN(c.cfg.Database.DSN, c.schema)
+	if err != nil {
+		return err
+	}
+	m, err := migrate.New(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return m.Up()
+}
+
+func (c *postgresCheck) openRepo(context.Context) error {
+	dsn, err := scopedDSN(c.cfg.Database.DSN, c.schema)
+	if err != nil {
+		return err
+	}
+	seq, err := repository.NewSequenceSource(c.cfg.Links)
+	if err != nil {
+		return err
+	}
+	dbCfg := c.cfg.Database
+	dbCfg.DSN, dbCfg.ConnectMaxAttempts = dsn, 1
+	repo, err := repository.NewPostgresRepo(dbCfg, seq, c.logger)
+	if err != nil {
+		return err
+	}
+	c.repo = repo
+	return nil
+}
+
+func (c *postgresCheck) closeRepo(context.Context) error {
+	return c.repo.Close()
+}
+
+func (c *postgresCheck) createLink(ctx context.Context) error {
+	expired := time.Now().Add(-time.Minute)
+	link, err := c.repo.CreateShortLink(ctx, models.NewShortLink{OriginalURL: selftestURL, ExpiresAt: &expired})
+	if err != nil {
+		return err
+	}
+	c.link = link
+	return nil
+}
+
+func (c *postgresCheck) resolveLink(ctx context.Context) error {
+	link, err := c.repo.GetShortLinkByCode(ctx, c.link.Code)
+	if err != nil {
+		return err
+	}
+	if link.OriginalURL != selftestURL {
+		return fmt.Errorf("code %s resolved to %q, want %q", c.link.Code, link.OriginalURL, selftestURL)
+	}
+	return nil
+}
+
+func (c *postgresCheck) deleteLink(ctx context.Context) error {
+	n, err := c.repo.DeleteExpiredShortLinks(ctx)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("deleted %d expired links, want 1", n)
+	}
+	_, err = c.repo.GetShortLinkByCode(ctx, c.link.Code)
+	switch {
+	case err == nil:
+		return fmt.Errorf("code %s still resolves after deletion", c.link.Code)
+	case !errors.Is(err, repository.ErrLinkNotFound):
+		return err
+	}
+	return nil
+}
+
+// scopedDSN returns dsn with its search_path set to schema, so that the
+// migrations and queries of a connection made with it use that schema. It
+// accepts both the URL and the key=value forms lib/pq understands.
+func scopedDSN(dsn, schema string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid database DSN: %w", err)
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return strings.TrimSpace(dsn) + " search_path=" + schema, nil
+}
@@ -0,0 +1,49 @@
+//go:build integration
+
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+// TestPostgres runs the database self-test against the scratch Postgres
+// database in SHORTLINK_TEST_DSN. Run it with
+// "go test -tags integration ./internal/selftest".
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("SHORTLINK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("SHORTLINK_TEST_DSN is not set")
+	}
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{DSN: dsn, ConnectMaxAttempts: 1},
+		Links:    config.LinkConfig{CodeSource: "serial"},
+	}
+
+	report := Run(context.Background(), Postgres(cfg, zap.NewNop()))
+	var out bytes.Buffer
+	_ = report.Write(&out)
+	if !report.OK() {
+		t.Fatalf("self-test failed:\n%s", out.String())
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+	var left int
+	if err := db.Get(&left, `SELECT count(*) FROM information_schema.schemata WHERE schema_name LIKE 'shortlink_selftest_%'`); err != nil {
+		t.Fatalf("count schemas: %v", err)
+	}
+	if left != 0 {
+		t.Errorf("%d scratch schemas left behind", left)
+	}
+}
@@ -0,0 +1,109 @@
+// Package selftest runs a short end-to-end check of the service against its
+// real dependencies and reports how each step went, for deploy pipelines
+// that need more than a ping. See Postgres for the steps.
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Step is one check. Cleanup, if set, undoes what Run did; it runs after
+// the remaining steps, and only if Run succeeded.
+type Step struct {
+	Name    string
+	Run     func(ctx context.Context) error
+	Cleanup func(ctx context.Context) error
+}
+
+// Result is the outcome of a step or of its cleanup.
+type Result struct {
+	Name     string
+	Skipped  bool
+	Err      error
+	Duration time.Duration
+}
+
+// Report lists the results in the order they ran: the steps, then the
+// cleanups.
+type Report struct {
+	Results []Result
+}
+
+// OK reports whether every step and cleanup that ran succeeded. A report
+// with skipped steps is not OK, since skipping follows a failure.
+func (r *Report) OK() bool {
+	for _, res := range r.Results {
+		if res.Skipped || res.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Write writes one line per result to w, for example
+//
+//	ok    migrate (41ms)
+//	FAIL  resolve link: short link not found
+//	skip  delete expired link
+func (r *Report) Write(w io.Writer) error {
+	for _, res := range r.Results {
+		var err error
+		switch {
+		case res.Skipped:
+			_, err = fmt.Fprintf(w, "skip  %s\n", res.Name)
+		case res.Err != nil:
+			_, err = fmt.Fprintf(w, "FAIL  %s: %v\n", res.Name, res.Err)
+		default:
+			_, err = fmt.Fprintf(w, "ok    %s (%s)\n", res.Name, res.Duration.Round(time.Millisecond))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run runs steps in order until one fails; the rest are skipped. It then
+// runs the cleanups of the steps that succeeded, last step first, even if
+// ctx is done by then, so that a failed or timed-out check still removes
+// what it created.
+func Run(ctx context.Context, steps []Step) *Report {
+	report := &Report{}
+	var cleanups []Step
+	failed := false
+	for _, step := range steps {
+		if failed {
+			report.Results = append(report.Results, Result{Name: step.Name, Skipped: true})
+			continue
+		}
+		res := run(ctx, step.Name, step.Run)
+		report.Results = append(report.Results, res)
+		if res.Err != nil {
+			failed = true
+			continue
+		}
+		if step.Cleanup != nil {
+			cleanups = append(cleanups, step)
+		}
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+	defer cancel()
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		report.Results = append(report.Results, run(cleanupCtx, "clean up "+cleanups[i].Name, cleanups[i].Cleanup))
+	}
+	return report
+}
+
+// cleanupTimeout bounds the cleanups, which run without the caller's
+// deadline.
+const cleanupTimeout = 30 * time.Second
+
+func run(ctx context.Context, name string, fn func(context.Context) error) Result {
+	start := time.Now()
+	err := fn(ctx)
+	return Result{Name: name, Err: err, Duration: time.Since(start)}
+}
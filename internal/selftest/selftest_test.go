@@ -0,0 +1,106 @@
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recorder builds steps that log when they run and clean up.
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) step(name string, err error) Step {
+	return Step{
+		Name: name,
+		Run: func(context.Context) error {
+			r.calls = append(r.calls, "run "+name)
+			return err
+		},
+		Cleanup: func(context.Context) error {
+			r.calls = append(r.calls, "clean up "+name)
+			return nil
+		},
+	}
+}
+
+func TestRunAllStepsPass(t *testing.T) {
+	rec := &recorder{}
+	report := Run(context.Background(), []Step{rec.step("a", nil), rec.step("b", nil), {Name: "c", Run: func(context.Context) error { return nil }}})
+	if !report.OK() {
+		t.Errorf("report is not OK: %+v", report.Results)
+	}
+	want := "run a,run b,clean up b,clean up a"
+	if got := strings.Join(rec.calls, ","); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+	if n := len(report.Results); n != 5 {
+		t.Errorf("report has %d results, want 3 steps and 2 cleanups", n)
+	}
+}
+
+func TestRunStopsAtFirstFailureAndCleansUp(t *testing.T) {
+	rec := &recorder{}
+	boom := errors.New("boom")
+	report := Run(context.Background(), []Step{rec.step("a", nil), rec.step("b", boom), rec.step("c", nil)})
+	if report.OK() {
+		t.Fatal("report is OK after a failed step")
+	}
+	// b failed, so only a is cleaned up and c never runs.
+	want := "run a,run b,clean up a"
+	if got := strings.Join(rec.calls, ","); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 ||
+		!strings.HasPrefix(lines[0], "ok    a (") ||
+		lines[1] != "FAIL  b: boom" ||
+		lines[2] != "skip  c" ||
+		!strings.HasPrefix(lines[3], "ok    clean up a (") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
+
+func TestRunCleansUpAfterCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var cleanupErr error
+	report := Run(ctx, []Step{
+		{
+			Name:    "create",
+			Run:     func(context.Context) error { return nil },
+			Cleanup: func(ctx context.Context) error { cleanupErr = ctx.Err(); return nil },
+		},
+		{Name: "hang", Run: func(ctx context.Context) error { cancel(); return ctx.Err() }},
+	})
+	if report.OK() {
+		t.Error("report is OK after a cancelled step")
+	}
+	if cleanupErr != nil {
+		t.Errorf("cleanup ran with a done context: %v", cleanupErr)
+	}
+}
+
+func TestScopedDSN(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{"postgres://u:p@db:5432/shortlink?sslmode=disable", "postgres://u:p@db:5432/shortlink?search_path=scratch&sslmode=disable"},
+		{"postgresql://db/shortlink?search_path=public", "postgresql://db/shortlink?search_path=scratch"},
+		{"host=db dbname=shortlink sslmode=disable", "host=db dbname=shortlink sslmode=disable search_path=scratch"},
+	}
+	for _, tt := range tests {
+		got, err := scopedDSN(tt.dsn, "scratch")
+		if err != nil || got != tt.want {
+			t.Errorf("scopedDSN(%q) = %q, %v; want %q", tt.dsn, got, err, tt.want)
+		}
+	}
+}
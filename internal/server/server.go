@@ -0,0 +1,160 @@
+// Package server wires the HTTP routes to their handlers.
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/config"
+	"github.com/maojcn/shortlink/internal/events"
+	"github.com/maojcn/shortlink/internal/handlers"
+	"github.com/maojcn/shortlink/internal/linkcheck"
+	"github.com/maojcn/shortlink/internal/middleware"
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/outbound"
+	"github.com/maojcn/shortlink/internal/respond"
+	"github.com/maojcn/shortlink/internal/worker"
+)
+
+// Repository is the storage the server needs. *repository.PostgresRepo
+// implements it.
+type Repository interface {
+	handlers.LinkRepository
+	handlers.LinkGetter
+	handlers.LinkLister
+	handlers.Pinger
+	handlers.ShareTokenRepository
+	middleware.APIKeyRepository
+	middleware.ShareTokenRepository
+	worker.ExpiredLinkDeleter
+}
+
+// Server owns the Gin engine and the handlers it routes to.
+type Server struct {
+	router *gin.Engine
+	repo   Repository
+	links  *handlers.LinkHandler
+	checks *handlers.CheckHandler
+	health *handlers.HealthHandler
+	qr     *handlers.QRHandler
+	shares *handlers.ShareTokenHandler
+	feeds  *handlers.FeedHandler
+
+	stopWorkers context.CancelFunc
+	workers     sync.WaitGroup
+}
+
+// New builds a Server backed by repo that publishes link events to
+// publisher and refuses the custom codes in reserved. Every request gets a
+// request ID and an access log line on logger; requests for a host outside
+// cfg.Server.AllowedHosts are refused, as are requests beyond
+// cfg.Server.MaxConnsPerIP in flight from one client IP, and the rest carry
+// a deadline of cfg.Server.RequestTimeout. Client IPs only come from
+// X-Forwarded-For behind cfg.Server.TrustedProxies. With tracing enabled
+// each request also gets a server span. JSON responses carry
+// cfg.Server.JSONCharset in their Content-Type and are indented on
+// ?pretty=true when cfg.Server.AllowPrettyJSON is set. With
+// cfg.Server.EmitServerTiming responses carry a Server-Timing header.
+// Destination checks go through an outbound.NewClient. A request that
+// matches no route gets a JSON 404, or a JSON 405 with an Allow header when
+// its path is routed for other methods. The background workers start
+// immediately; call Shutdown to stop them.
+func New(cfg *config.Config, repo Repository, publisher events.Publisher, reserved handlers.ReservedCodes, logger *zap.Logger) *Server {
+	checker := linkcheck.New(outbound.NewClient(cfg.Outbound), cfg.LinkCheck)
+	s := &Server{
+		router: gin.New(),
+		repo:   repo,
+		links:  handlers.NewLinkHandler(repo, publisher, reserved, cfg),
+		checks: handlers.NewCheckHandler(repo, checker, cfg.LinkCheck.MaxLinks),
+		health: handlers.NewHealthHandler(map[string]handlers.Pinger{"postgres": repo}),
+		qr:     handlers.NewQRHandler(repo, cfg.Server.BaseURL, cfg.Links.QRCacheMaxAge),
+		shares: handlers.NewShareTokenHandler(repo),
+		feeds:  handlers.NewFeedHandler(repo, cfg.Server.BaseURL),
+	}
+	s.router.HandleMethodNotAllowed = true
+	// config.Load has validated the list, so this cannot fail.
+	_ = s.router.SetTrustedProxies(cfg.Server.TrustedProxies)
+	if cfg.Tracing.Enabled {
+		s.router.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	}
+	s.router.Use(
+		respond.Use(respond.Options{AllowPretty: cfg.Server.AllowPrettyJSON, Charset: cfg.Server.JSONCharset}),
+		middleware.RequestID(),
+		middleware.ServerTiming(cfg.Server.EmitServerTiming),
+		middleware.Logger(logger),
+		middleware.AllowedHosts(cfg.Server.AllowedHosts),
+		middleware.PerIPConcurrency(cfg.Server.MaxConnsPerIP, cfg.Server.ConnLimitExempt),
+		gin.Recovery(),
+		middleware.Timeout(cfg.Server.RequestTimeout),
+	)
+	s.setupRoutes()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopWorkers = cancel
+	sweeper := worker.NewSweeper(repo, cfg.Worker.SweepInterval, logger)
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		sweeper.Run(ctx)
+	}()
+	return s
+}
+
+func (s *Server) setupRoutes() {
+	s.router.NoRoute(handlers.NotFound)
+	s.router.NoMethod(handlers.MethodNotAllowed)
+
+	for path, probe := range map[string]gin.HandlerFunc{
+		"/health":       s.health.Ready,
+		"/health/live":  s.health.Live,
+		"/health/ready": s.health.Ready,
+		"/livez":        s.health.Live,
+		"/readyz":       s.health.Ready,
+	} {
+		s.router.GET(path, probe)
+		s.router.HEAD(path, probe)
+	}
+
+	s.router.GET("/api/v1", handlers.APIIndex)
+
+	// Feed readers cannot send an API key, so the feed also accepts a feed
+	// share token and sits outside the API-key-only group.
+	s.router.GET("/api/v1/me/links/feed.xml",
+		middleware.ShareTokenOrAPIKeyAuth(s.repo, models.ShareScopeFeed, s.repo), s.feeds.LinksFeed)
+
+	v1 := s.router.Group("/api/v1", middleware.APIKeyAuth(s.repo))
+	v1.POST("/links", s.links.CreateShortLink)
+	v1.POST("/links/batch", s.links.BatchCreateShortLinks)
+	v1.POST("/links/import", s.links.ImportLinks)
+	v1.GET("/links/:code/qr", s.qr.QRCode)
+	v1.POST("/me/links/check", s.checks.CheckLinks)
+	v1.POST("/me/share-tokens", s.shares.CreateShareToken)
+	v1.DELETE("/me/share-tokens/:id", s.shares.RevokeShareToken)
+}
+
+// Handler returns the http.Handler to serve.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// Shutdown stops the background workers and waits for them to finish, or
+// for ctx to be done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,417 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+
+	"github.com/maojcn/shortlink/internal/config"
+	"github.com/maojcn/shortlink/internal/events"
+	"github.com/maojcn/shortlink/internal/models"
+	"github.com/maojcn/shortlink/internal/repository"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Events: config.EventsConfig{Source: "/test"},
+		Server: config.ServerConfig{BaseURL: "https://sho.rt"},
+		Worker: config.WorkerConfig{SweepInterval: time.Millisecond},
+	}
+}
+
+type fakeRepo struct {
+	created []string
+	sweeps  atomic.Int32
+}
+
+const testShareToken = "st_feed"
+
+func (f *fakeRepo) CreateShareToken(_ context.Context, userID int64, scope string) (*models.ShareToken, error) {
+	return &models.ShareToken{ID: 1, UserID: userID, Scope: scope, Token: testShareToken}, nil
+}
+
+func (f *fakeRepo) RevokeShareToken(context.Context, int64, int64) error {
+	return nil
+}
+
+func (f *fakeRepo) UserIDForShareToken(_ context.Context, token, scope string) (int64, error) {
+	if token != testShareToken || scope != models.ShareScopeFeed {
+		return 0, repository.ErrShareTokenNotFound
+	}
+	return 7, nil
+}
+
+func (f *fakeRepo) BatchCreateShortLinks(_ context.Context, items []models.NewShortLink) ([]repository.BatchResult, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) GetShortLinkByCode(_ context.Context, code string) (*models.ShortLink, error) {
+	if code != "abc1234" {
+		return nil, repository.ErrLinkNotFound
+	}
+	return &models.ShortLink{ID: 1, Code: code, OriginalURL: "https://example.com"}, nil
+}
+
+func (f *fakeRepo) Ping(context.Context) error {
+	return nil
+}
+
+func (f *fakeRepo) DeleteExpiredShortLinks(context.Context) (int64, error) {
+	f.sweeps.Add(1)
+	return 0, nil
+}
+
+// newTestServer builds a Server and shuts it down when the test ends.
+func newTestServer(t *testing.T, repo *fakeRepo) *Server {
+	t.Helper()
+	s := New(testConfig(), repo, events.NopPublisher{}, nil, zap.NewNop())
+	t.Cleanup(func() {
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+	return s
+}
+
+const testAPIKey = "test-key"
+
+func (f *fakeRepo) ListUserShortLinks(_ context.Context, userID int64, codes []string, limit int) ([]models.ShortLink, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) UserIDForAPIKey(_ context.Context, key string) (int64, error) {
+	if key != testAPIKey {
+		return 0, repository.ErrAPIKeyNotFound
+	}
+	return 7, nil
+}
+
+func (f *fakeRepo) CreateShortLink(_ context.Context, in models.NewShortLink) (*models.ShortLink, error) {
+	f.created = append(f.created, in.OriginalURL)
+	return &models.ShortLink{ID: 1, Code: "abc1234", OriginalURL: in.OriginalURL, UserID: in.UserID}, nil
+}
+
+func TestCreateLinkRoute(t *testing.T) {
+	repo := &fakeRepo{}
+	h := newTestServer(t, repo).Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"original_url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", testAPIKey)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if w.Header().Get("X-Request-ID") == "" {
+		t.Error("response has no X-Request-ID")
+	}
+	var resp struct {
+		Data models.ShortLink `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.Code != "abc1234" || resp.Data.UserID != 7 {
+		t.Errorf("link = %+v, want code %q owned by user 7", resp.Data, "abc1234")
+	}
+	if len(repo.created) != 1 || repo.created[0] != "https://example.com" {
+		t.Errorf("repository calls = %v", repo.created)
+	}
+}
+
+func TestCreateLinkRouteRequiresAPIKey(t *testing.T) {
+	repo := &fakeRepo{}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"original_url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	newTestServer(t, repo).Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if len(repo.created) != 0 {
+		t.Errorf("link created without an API key: %v", repo.created)
+	}
+}
+
+func TestShareTokenCannotCreateLinks(t *testing.T) {
+	repo := &fakeRepo{}
+	h := newTestServer(t, repo).Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/me/share-tokens", strings.NewReader(`{"scope":"feed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", testAPIKey)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), testShareToken) {
+		t.Fatalf("mint: status = %d, body %s", w.Code, w.Body)
+	}
+
+	for _, auth := range []func(*http.Request){
+		func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+testShareToken) },
+		func(r *http.Request) { r.Header.Set("X-Share-Token", testShareToken) },
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links?token="+testShareToken, strings.NewReader(`{"original_url":"https://example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		auth(req)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+	}
+	if len(repo.created) != 0 {
+		t.Errorf("link created with a share token: %v", repo.created)
+	}
+}
+
+func TestHealthProbeRoutes(t *testing.T) {
+	h := newTestServer(t, &fakeRepo{}).Handler()
+	for _, path := range []string{"/health", "/health/live", "/health/ready", "/livez", "/readyz"} {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("%s %s: status = %d, want %d", method, path, w.Code, http.StatusOK)
+			}
+		}
+	}
+}
+
+func TestUnroutedRequests(t *testing.T) {
+	h := newTestServer(t, &fakeRepo{}).Handler()
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantCode  int
+		wantAllow string
+		wantError string
+	}{
+		{"wrong method", http.MethodDelete, "/health", http.StatusMethodNotAllowed, "GET, HEAD", "method not allowed"},
+		{"wrong method on API route", http.MethodGet, "/api/v1/links/batch", http.StatusMethodNotAllowed, "POST", "method not allowed"},
+		{"no route", http.MethodGet, "/nope/at/all", http.StatusNotFound, "", "route not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", ct)
+			}
+			var resp models.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error != tt.wantError {
+				t.Errorf("body = %s, want error %q", w.Body, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAPIIndexMatchesRoutes(t *testing.T) {
+	srv := newTestServer(t, &fakeRepo{})
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status without credentials = %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp struct {
+		Data models.APIIndex `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	routes := make(map[string]bool)
+	for _, r := range srv.router.Routes() {
+		routes[r.Method+" "+r.Path] = true
+	}
+	for _, col := range resp.Data.Collections {
+		for _, m := range col.Methods {
+			if !routes[m+" "+col.Href] {
+				t.Errorf("index lists %s %s, which is not a route", m, col.Href)
+			}
+		}
+	}
+}
+
+func TestFeedRouteAuth(t *testing.T) {
+	h := newTestServer(t, &fakeRepo{}).Handler()
+	tests := []struct {
+		name     string
+		url      string
+		apiKey   string
+		wantCode int
+	}{
+		{"share token", "/api/v1/me/links/feed.xml?token=" + testShareToken, "", http.StatusOK},
+		{"api key", "/api/v1/me/links/feed.xml", testAPIKey, http.StatusOK},
+		{"revoked or unknown token", "/api/v1/me/links/feed.xml?token=st_other", "", http.StatusUnauthorized},
+		{"no credentials", "/api/v1/me/links/feed.xml", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.apiKey != "" {
+			req.Header.Set("X-API-Key", tt.apiKey)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantCode)
+		}
+	}
+}
+
+func TestQRCodeRoute(t *testing.T) {
+	h := newTestServer(t, &fakeRepo{}).Handler()
+	for code, want := range map[string]int{"abc1234": http.StatusOK, "missing": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/"+code+"/qr", nil)
+		req.Header.Set("X-API-Key", testAPIKey)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: status = %d, want %d", code, w.Code, want)
+		}
+	}
+}
+
+func TestHealthRoutesSkipAuth(t *testing.T) {
+	h := newTestServer(t, &fakeRepo{}).Handler()
+	for _, path := range []string{"/health", "/health/live", "/health/ready"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", path, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		cfg := testConfig()
+		cfg.Server.AllowPrettyJSON = allow
+		s := New(cfg, &fakeRepo{}, events.NopPublisher{}, nil, zap.NewNop())
+		t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live?pretty=true", nil))
+		if got := strings.Contains(w.Body.String(), "\n"); got != allow {
+			t.Errorf("AllowPrettyJSON=%v: indented = %v; body %q", allow, got, w.Body)
+		}
+	}
+}
+
+func TestServerTimingHeader(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		cfg := testConfig()
+		cfg.Server.EmitServerTiming = enabled
+		s := New(cfg, &fakeRepo{}, events.NopPublisher{}, nil, zap.NewNop())
+		t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+		h := w.Header().Get("Server-Timing")
+		if got := strings.HasPrefix(h, "db;dur="); got != enabled {
+			t.Errorf("EmitServerTiming=%v: Server-Timing = %q", enabled, h)
+		}
+	}
+}
+
+func TestJSONContentType(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.JSONCharset = "utf-8"
+	s := New(cfg, &fakeRepo{}, events.NopPublisher{}, nil, zap.NewNop())
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"original_url":"https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", testAPIKey)
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want %q", got, "application/json; charset=utf-8")
+	}
+}
+
+func TestAllowedHosts(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.AllowedHosts = []string{"sho.rt"}
+	s := New(cfg, &fakeRepo{}, events.NopPublisher{}, nil, zap.NewNop())
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+	for host, want := range map[string]int{"sho.rt": http.StatusOK, "evil.example": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("Host %s: status = %d, want %d", host, w.Code, want)
+		}
+	}
+}
+
+func TestTracingAddsServerSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	cfg := testConfig()
+	cfg.Tracing = config.TracingConfig{Enabled: true, ServiceName: "shortlink-test"}
+	s := New(cfg, &fakeRepo{}, events.NopPublisher{}, nil, zap.NewNop())
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+	s.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	spans := rec.Ended()
+	if len(spans) != 1 || spans[0].Name() != "GET /health/live" {
+		t.Errorf("spans = %v, want one server span for the route", spans)
+	}
+}
+
+func TestUnknownRouteIsNotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	newTestServer(t, &fakeRepo{}).Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestShutdownStopsSweeper(t *testing.T) {
+	repo := &fakeRepo{}
+	s := New(testConfig(), repo, events.NopPublisher{}, nil, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	n := repo.sweeps.Load()
+	if n < 1 {
+		t.Error("sweeper never ran")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if repo.sweeps.Load() != n {
+		t.Error("sweeper kept running after Shutdown")
+	}
+}
@@ -0,0 +1,76 @@
+// Package servertiming collects where a request spent its time and formats
+// it as a Server-Timing header (https://www.w3.org/TR/server-timing/).
+package servertiming
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metric names used in the header.
+const (
+	MetricDB    = "db"
+	MetricApp   = "app"
+	MetricTotal = "total"
+)
+
+type timingsKey struct{}
+
+// Timings accumulates the time a request spent in the database. It is safe
+// for concurrent use.
+type Timings struct {
+	start time.Time
+
+	mu      sync.Mutex
+	db      time.Duration
+	queries int
+}
+
+// NewContext returns ctx carrying new Timings whose total starts now.
+func NewContext(ctx context.Context) (context.Context, *Timings) {
+	t := &Timings{start: time.Now()}
+	return context.WithValue(ctx, timingsKey{}, t), t
+}
+
+// FromContext returns the Timings of ctx, or nil when the request is not
+// timed. Callers check for nil before reading the clock, so untimed
+// requests pay for one context lookup only.
+func FromContext(ctx context.Context) *Timings {
+	t, _ := ctx.Value(timingsKey{}).(*Timings)
+	return t
+}
+
+// AddQuery records one database call that took d.
+func (t *Timings) AddQuery(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.db += d
+	t.queries++
+}
+
+// Header returns the Server-Timing value as of now, for example
+//
+//	db;dur=1.20;desc="queries: 2", app;dur=0.35, total;dur=1.55
+//
+// app is the time spent outside the database. Durations are in
+// milliseconds.
+func (t *Timings) Header(now time.Time) string {
+	t.mu.Lock()
+	db, queries := t.db, t.queries
+	t.mu.Unlock()
+
+	total := now.Sub(t.start)
+	app := max(total-db, 0)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s;dur=%s;desc=\"queries: %d\"", MetricDB, ms(db), queries)
+	fmt.Fprintf(&b, ", %s;dur=%s", MetricApp, ms(app))
+	fmt.Fprintf(&b, ", %s;dur=%s", MetricTotal, ms(total))
+	return b.String()
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.2f", float64(d.Microseconds())/1000)
+}
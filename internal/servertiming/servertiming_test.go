@@ -0,0 +1,38 @@
+package servertiming
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestHeader(t *testing.T) {
+	ctx, timings := NewContext(context.Background())
+	if FromContext(ctx) != timings {
+		t.Fatal("FromContext did not return the Timings of NewContext")
+	}
+	timings.AddQuery(1500 * time.Microsecond)
+	timings.AddQuery(500 * time.Microsecond)
+
+	got := timings.Header(timings.start.Add(5 * time.Millisecond))
+	want := `db;dur=2.00;desc="queries: 2", app;dur=3.00, total;dur=5.00`
+	if got != want {
+		t.Errorf("Header = %q, want %q", got, want)
+	}
+}
+
+func TestHeaderIsWellFormed(t *testing.T) {
+	_, timings := NewContext(context.Background())
+	metric := `[a-z]+;dur=\d+\.\d{2}(;desc="[^"]*")?`
+	re := regexp.MustCompile(`^` + metric + `(, ` + metric + `)*$`)
+	if h := timings.Header(time.Now()); !re.MatchString(h) {
+		t.Errorf("Header %q is not a valid Server-Timing value", h)
+	}
+}
+
+func TestFromContextWithoutTimings(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Error("FromContext returned Timings for an untimed context")
+	}
+}
@@ -0,0 +1,71 @@
+// Package shortcode converts numeric link IDs to and from base62 short codes.
+package shortcode
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// Alphabet is the base62 digit set, ordered 0-9, A-Z, a-z.
+const Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+const base = int64(len(Alphabet))
+
+var (
+	// ErrEmptyCode is returned by Decode when the code is empty.
+	ErrEmptyCode = errors.New("shortcode: empty code")
+	// ErrInvalidCharacter is returned by Decode when the code contains a
+	// character outside Alphabet.
+	ErrInvalidCharacter = errors.New("shortcode: invalid character")
+	// ErrOverflow is returned by Decode when the code does not fit in an int64.
+	ErrOverflow = errors.New("shortcode: value overflows int64")
+	// ErrNonCanonical is returned by Decode when the code has leading zero
+	// digits, so that every ID has exactly one valid code.
+	ErrNonCanonical = errors.New("shortcode: non-canonical code")
+)
+
+// Encode returns the base62 representation of id. It panics if id is
+// negative, since database IDs never are.
+func Encode(id int64) string {
+	if id < 0 {
+		panic("shortcode: negative id")
+	}
+	if id == 0 {
+		return Alphabet[:1]
+	}
+
+	// 11 base62 digits are enough for math.MaxInt64.
+	var buf [11]byte
+	i := len(buf)
+	for id > 0 {
+		i--
+		buf[i] = Alphabet[id%base]
+		id /= base
+	}
+	return string(buf[i:])
+}
+
+// Decode parses a base62 code produced by Encode back into its ID. Only the
+// canonical form is accepted: "01" is rejected rather than decoded as 1.
+func Decode(code string) (int64, error) {
+	if code == "" {
+		return 0, ErrEmptyCode
+	}
+	if len(code) > 1 && code[0] == Alphabet[0] {
+		return 0, ErrNonCanonical
+	}
+
+	var id int64
+	for _, r := range code {
+		digit := strings.IndexRune(Alphabet, r)
+		if digit < 0 {
+			return 0, ErrInvalidCharacter
+		}
+		if id > (math.MaxInt64-int64(digit))/base {
+			return 0, ErrOverflow
+		}
+		id = id*base + int64(digit)
+	}
+	return id, nil
+}
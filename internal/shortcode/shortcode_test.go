@@ -0,0 +1,74 @@
+package shortcode
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ids := []int64{0, 1, 61, 62, 63, 3843, 3844, 1 << 40, math.MaxInt64 - 1, math.MaxInt64}
+	for _, id := range ids {
+		code := Encode(id)
+		got, err := Decode(code)
+		if err != nil {
+			t.Fatalf("Decode(Encode(%d)) = %q: unexpected error: %v", id, code, err)
+		}
+		if got != id {
+			t.Errorf("Decode(Encode(%d)) = %d (code %q)", id, got, code)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		id   int64
+		want string
+	}{
+		{0, "0"},
+		{1, "1"},
+		{61, "z"},
+		{62, "10"},
+		{3843, "zz"},
+		{math.MaxInt64, "AzL8n0Y58m7"},
+	}
+	for _, tt := range tests {
+		if got := Encode(tt.id); got != tt.want {
+			t.Errorf("Encode(%d) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeNegativePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Encode(-1) did not panic")
+		}
+	}()
+	Encode(-1)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{"empty", "", ErrEmptyCode},
+		{"hyphen", "ab-c", ErrInvalidCharacter},
+		{"underscore", "a_b", ErrInvalidCharacter},
+		{"space", "ab c", ErrInvalidCharacter},
+		{"non-ascii", "é", ErrInvalidCharacter},
+		{"leading zero", "01", ErrNonCanonical},
+		{"leading zeros", "0001", ErrNonCanonical},
+		{"max plus one", "AzL8n0Y58m8", ErrOverflow},
+		{"too long", "zzzzzzzzzzzz", ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.code); !errors.Is(err, tt.want) {
+				t.Errorf("Decode(%q) error = %v, want %v", tt.code, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/maojcn/shortlink/internal/config"
+)
+
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+	tp, prop := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(prop)
+	})
+}
+
+func TestSetupDisabled(t *testing.T) {
+	restoreGlobals(t)
+	before := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	if otel.GetTracerProvider() != before {
+		t.Error("disabled tracing replaced the global tracer provider")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+}
+
+func TestSetupEnabled(t *testing.T) {
+	restoreGlobals(t)
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Enabled:     true,
+		Endpoint:    "http://127.0.0.1:4318",
+		SampleRatio: 1,
+		ServiceName: "shortlink-test",
+	})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); !ok {
+		t.Errorf("global tracer provider is %T, want the SDK provider", otel.GetTracerProvider())
+	}
+	// No spans were started, so shutting down exports nothing.
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+}
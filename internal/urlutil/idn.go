@@ -0,0 +1,133 @@
+package urlutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidHost is returned by Normalize when the host is not a valid
+// internationalized domain name.
+var ErrInvalidHost = errors.New("urlutil: invalid host")
+
+// Normalize returns raw with a lowercase scheme and its host in canonical
+// ASCII (punycode) form, so "https://MÜNCHEN.de/x" becomes
+// "https://xn--mnchen-3ya.de/x". IP literals are left as they are. The
+// rest of the URL is not changed.
+func Normalize(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Host == "" {
+		return u.String(), nil
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if net.ParseIP(host) == nil {
+		ascii, err := idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q: %v", ErrInvalidHost, host, err)
+		}
+		host = ascii
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	return u.String(), nil
+}
+
+// Display returns raw with a punycode host converted back to Unicode for
+// showing to people. raw is returned unchanged if it cannot be converted.
+func Display(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	host, err := idna.Display.ToUnicode(u.Hostname())
+	if err != nil {
+		return raw
+	}
+
+	// url.URL.String would percent-encode a Unicode host, so splice it
+	// into the authority of raw instead.
+	start := strings.Index(raw, "//") + 2
+	end := start + len(authority(raw[start:]))
+	if at := strings.LastIndexByte(raw[start:end], '@'); at >= 0 {
+		start += at + 1
+	}
+	hostStart := start + strings.Index(strings.ToLower(raw[start:end]), strings.ToLower(u.Hostname()))
+	if hostStart < start {
+		return raw
+	}
+	return raw[:hostStart] + host + raw[hostStart+len(u.Hostname()):]
+}
+
+// latinLookalikes are Cyrillic and Greek letters that render like Latin
+// ones in common fonts.
+const latinLookalikes = "аеорсухіјѕԁһӏԛԝвкмнтоοαεικνρτυχ"
+
+// IsConfusable reports whether host, in Unicode or punycode form, has a
+// label that could pass for a different domain: one mixing letters from
+// several scripts ("pаypal" with a Cyrillic а), or one written entirely
+// in Latin lookalikes from another script ("аррlе" all Cyrillic) under a
+// non-matching top-level domain.
+func IsConfusable(host string) bool {
+	if u, err := idna.Display.ToUnicode(host); err == nil {
+		host = u
+	}
+	labels := strings.Split(strings.ToLower(host), ".")
+	tldScript := labelScript(labels[len(labels)-1])
+	for _, label := range labels {
+		script := labelScript(label)
+		if script == mixedScript {
+			return true
+		}
+		if script != nil && script != unicode.Latin && script != tldScript && allLookalikes(label) {
+			return true
+		}
+	}
+	return false
+}
+
+// mixedScript is returned by labelScript for labels using several scripts.
+var mixedScript = &unicode.RangeTable{}
+
+var scripts = []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Greek, unicode.Armenian}
+
+// labelScript returns the single script the letters of label belong to,
+// mixedScript for more than one, or nil when it has no letters from the
+// scripts checked.
+func labelScript(label string) *unicode.RangeTable {
+	var found *unicode.RangeTable
+	for _, r := range label {
+		for _, s := range scripts {
+			if !unicode.Is(s, r) {
+				continue
+			}
+			if found != nil && found != s {
+				return mixedScript
+			}
+			found = s
+		}
+	}
+	return found
+}
+
+func allLookalikes(label string) bool {
+	for _, r := range label {
+		if unicode.IsLetter(r) && !strings.ContainsRune(latinLookalikes, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package urlutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://münchen.de/a?b=c", "https://xn--mnchen-3ya.de/a?b=c"},
+		{"HTTPS://MÜNCHEN.DE", "https://xn--mnchen-3ya.de"},
+		{"https://xn--mnchen-3ya.de", "https://xn--mnchen-3ya.de"},
+		{"https://bücher.example:8443/x", "https://xn--bcher-kva.example:8443/x"},
+		{"https://Example.COM/Path", "https://example.com/Path"},
+		{"http://127.0.0.1:8080/", "http://127.0.0.1:8080/"},
+		{"http://[::1]:8080/", "http://[::1]:8080/"},
+		{"https://例え.jp", "https://xn--r8jz45g.jp"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRejectsInvalidHost(t *testing.T) {
+	for _, in := range []string{"https://exa mple.com", "https://a‍b.com", "https://-bad-.com"} {
+		if got, err := Normalize(in); err == nil {
+			t.Errorf("Normalize(%q) = %q, want error", in, got)
+		} else if !errors.Is(err, ErrInvalidHost) && in != "https://exa mple.com" {
+			t.Errorf("Normalize(%q) error = %v, want ErrInvalidHost", in, err)
+		}
+	}
+}
+
+func TestDisplayRoundTrip(t *testing.T) {
+	for _, in := range []string{"https://münchen.de/a?b=c", "https://bücher.example:8443/x", "https://例え.jp", "https://example.com/"} {
+		ascii, err := Normalize(in)
+		if err != nil {
+			t.Fatalf("Normalize(%q): %v", in, err)
+		}
+		if got := Display(ascii); got != in {
+			t.Errorf("Display(Normalize(%q)) = %q", in, got)
+		}
+	}
+}
+
+func TestIsConfusable(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", false},
+		{"münchen.de", false},
+		{"xn--mnchen-3ya.de", false},
+		{"пример.рф", false},
+		{"例え.jp", false},
+		{"pаypal.com", true},        // Cyrillic а among Latin letters
+		{"xn--pypal-4ve.com", true}, // the same host in punycode
+		{"аррlе.com", true},         // Cyrillic а, р and Latin l, е mixed
+		{"аррӏе.com", true},         // entirely Cyrillic lookalikes under .com
+		{"ωmega.com", true},         // Greek and Latin mixed
+		{"сайт.рф", false},          // Cyrillic under a Cyrillic TLD
+		{"ехо.рф", false},           // lookalikes, but the TLD matches
+	}
+	for _, tt := range tests {
+		if got := IsConfusable(tt.host); got != tt.want {
+			t.Errorf("IsConfusable(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
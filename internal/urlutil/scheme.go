@@ -0,0 +1,81 @@
+// Package urlutil normalizes destination URLs before they are validated and
+// stored.
+package urlutil
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnsureScheme prepends scheme + "://" to raw when raw clearly lacks a scheme
+// and starts with something that looks like a host, such as "example.com" or
+// "example.com:8080/path". Scheme-relative input ("//example.com") gets only
+// the scheme.
+//
+// Anything else is returned unchanged, including input that already has a
+// scheme and ambiguous input such as "localhost", "/path" or
+// "user@example.com", so that strict validation can still reject it.
+func EnsureScheme(raw, scheme string) string {
+	s := strings.TrimSpace(raw)
+	if strings.HasPrefix(s, "//") {
+		if looksLikeHost(authority(s[2:])) {
+			return scheme + ":" + s
+		}
+		return raw
+	}
+	if strings.Contains(s, "://") {
+		return raw
+	}
+	if looksLikeHost(authority(s)) {
+		return scheme + "://" + s
+	}
+	return raw
+}
+
+// authority returns the part of s before the first '/', '?' or '#'.
+func authority(s string) string {
+	if i := strings.IndexAny(s, "/?#"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// looksLikeHost reports whether a is a dotted domain name with an optional
+// numeric port, e.g. "example.com" or "münchen.de:8443". IP literals and
+// single-label names are deliberately not accepted.
+func looksLikeHost(a string) bool {
+	host := a
+	if i := strings.LastIndexByte(a, ':'); i >= 0 {
+		port, err := strconv.Atoi(a[i+1:])
+		if err != nil || port <= 0 || port > 65535 {
+			return false
+		}
+		host = a[:i]
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return false
+			}
+		}
+	}
+
+	// The top-level label must be alphabetic, which rules out "1.2.3.4"
+	// and version-like strings such as "1.5".
+	tld := labels[len(labels)-1]
+	for _, r := range tld {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return len([]rune(tld)) >= 2
+}
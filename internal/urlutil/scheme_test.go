@@ -0,0 +1,55 @@
+package urlutil
+
+import "testing"
+
+func TestEnsureScheme(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// Scheme-less hosts get the default scheme.
+		{"bare host", "example.com", "https://example.com"},
+		{"host with path", "example.com/a/b?c=d#e", "https://example.com/a/b?c=d#e"},
+		{"subdomain", "www.example.co.uk", "https://www.example.co.uk"},
+		{"host with port", "example.com:8080/x", "https://example.com:8080/x"},
+		{"idn host", "münchen.de", "https://münchen.de"},
+		{"scheme relative", "//example.com/x", "https://example.com/x"},
+		{"surrounding space", "  example.com ", "https://example.com"},
+
+		// Already-schemed input is untouched.
+		{"https", "https://example.com", "https://example.com"},
+		{"http upper", "HTTP://example.com", "HTTP://example.com"},
+		{"ftp", "ftp://example.com/file", "ftp://example.com/file"},
+
+		// Ambiguous input is left for validation to reject.
+		{"empty", "", ""},
+		{"single label", "localhost", "localhost"},
+		{"single label port", "localhost:8080", "localhost:8080"},
+		{"absolute path", "/just/a/path", "/just/a/path"},
+		{"word", "foo", "foo"},
+		{"userinfo", "user@example.com", "user@example.com"},
+		{"mailto", "mailto:a@example.com", "mailto:a@example.com"},
+		{"javascript", "javascript:alert(1)", "javascript:alert(1)"},
+		{"ipv4", "192.168.0.1", "192.168.0.1"},
+		{"version", "1.5", "1.5"},
+		{"bad port", "example.com:http", "example.com:http"},
+		{"port out of range", "example.com:70000", "example.com:70000"},
+		{"empty label", "example..com", "example..com"},
+		{"hyphen edge", "-example.com", "-example.com"},
+		{"scheme relative junk", "//foo", "//foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EnsureScheme(tt.in, "https"); got != tt.want {
+				t.Errorf("EnsureScheme(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureSchemeCustomScheme(t *testing.T) {
+	if got := EnsureScheme("example.com", "http"); got != "http://example.com" {
+		t.Errorf("EnsureScheme = %q, want %q", got, "http://example.com")
+	}
+}
@@ -0,0 +1,93 @@
+// Package validation defines the service's custom validator tags, so that
+// request structs can declare domain rules in their binding tags:
+//
+//	CustomCode string `binding:"omitempty,shortcode"`
+//	OriginalURL string `binding:"required,safeurl"`
+package validation
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Tags registered by Register.
+const (
+	// TagShortCode accepts codes users may choose for their links: 3-32
+	// letters, digits, '_' or '-'.
+	TagShortCode = "shortcode"
+	// TagSafeURL accepts destinations that can be stored and redirected
+	// to: no whitespace or control characters and, when a scheme is given,
+	// http or https. A scheme-less "example.com/a" passes so that the
+	// handler can decide whether to prefix a default scheme.
+	TagSafeURL = "safeurl"
+)
+
+// ShortCodePattern is the pattern TagShortCode enforces.
+var ShortCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+
+// Register adds the custom tags to v.
+func Register(v *validator.Validate) error {
+	if err := v.RegisterValidation(TagShortCode, isShortCode); err != nil {
+		return err
+	}
+	return v.RegisterValidation(TagSafeURL, isSafeURL)
+}
+
+var registerGin sync.Once
+
+// RegisterGin adds the custom tags to gin's binding validator. It is safe
+// to call more than once.
+func RegisterGin() {
+	registerGin.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			panic("validation: gin's binding validator is not a *validator.Validate")
+		}
+		if err := Register(v); err != nil {
+			panic("validation: " + err.Error())
+		}
+	})
+}
+
+func isShortCode(fl validator.FieldLevel) bool {
+	return ShortCodePattern.MatchString(fl.Field().String())
+}
+
+func isSafeURL(fl validator.FieldLevel) bool {
+	return SafeURL(fl.Field().String())
+}
+
+// SafeURL reports whether raw satisfies TagSafeURL.
+func SafeURL(raw string) bool {
+	if raw == "" || strings.IndexFunc(raw, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return false
+	}
+	if i := strings.Index(raw, "://"); i >= 0 {
+		scheme := strings.ToLower(raw[:i])
+		if scheme != "http" && scheme != "https" {
+			return false
+		}
+		_, err := url.Parse(raw)
+		return err == nil
+	}
+	// Without "://" a colon before any '/' is a scheme such as
+	// "javascript:" or "data:", unless it is a port.
+	head := raw
+	if j := strings.IndexAny(head, "/?#"); j >= 0 {
+		head = head[:j]
+	}
+	if i := strings.IndexByte(head, ':'); i >= 0 {
+		for _, r := range head[i+1:] {
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+type sample struct {
+	Code string `binding:"omitempty,shortcode"`
+	URL  string `binding:"required,safeurl"`
+}
+
+func newValidator(t *testing.T) *validator.Validate {
+	t.Helper()
+	v := validator.New()
+	v.SetTagName("binding")
+	if err := Register(v); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return v
+}
+
+// failedTag returns the tag s failed on, or "" if it is valid.
+func failedTag(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("error = %v, want validation errors", err)
+	}
+	return verrs[0].Tag()
+}
+
+func TestShortCodeTag(t *testing.T) {
+	v := newValidator(t)
+	for code, want := range map[string]string{
+		"":                                  "",
+		"abc":                               "",
+		"my-promo_1":                        "",
+		"ab":                                TagShortCode,
+		"a/b/c":                             TagShortCode,
+		"münchen":                           TagShortCode,
+		"abcdefghijklmnopqrstuvwxyz0123456": TagShortCode,
+	} {
+		if got := failedTag(t, v.Struct(sample{Code: code, URL: "https://example.com"})); got != want {
+			t.Errorf("code %q: failed tag = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestSafeURLTag(t *testing.T) {
+	v := newValidator(t)
+	for raw, want := range map[string]string{
+		"https://example.com/a?b=c": "",
+		"HTTP://example.com":        "",
+		"example.com/a":             "",
+		"example.com:8080/a":        "",
+		"//example.com":             "",
+		"":                          "required",
+		"ftp://example.com":         TagSafeURL,
+		"javascript:alert(1)":       TagSafeURL,
+		"data:text/html,hi":         TagSafeURL,
+		"https://exa mple.com":      TagSafeURL,
+		"https://example.com/\n":    TagSafeURL,
+		"https://[::1":              TagSafeURL,
+	} {
+		if got := failedTag(t, v.Struct(sample{URL: raw})); got != want {
+			t.Errorf("url %q: failed tag = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestRegisterGin(t *testing.T) {
+	RegisterGin()
+	RegisterGin() // idempotent
+	if err := binding.Validator.ValidateStruct(&sample{Code: "ab", URL: "https://example.com"}); failedTag(t, err) != TagShortCode {
+		t.Errorf("gin validation error = %v, want %s failure", err, TagShortCode)
+	}
+}
@@ -0,0 +1,59 @@
+// Package worker contains the background jobs the server runs.
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultSweepInterval is used when the configured interval is not
+// positive.
+const DefaultSweepInterval = time.Hour
+
+// ExpiredLinkDeleter removes expired links. *repository.PostgresRepo
+// implements it.
+type ExpiredLinkDeleter interface {
+	DeleteExpiredShortLinks(ctx context.Context) (int64, error)
+}
+
+// Sweeper periodically deletes expired links from Postgres.
+type Sweeper struct {
+	repo     ExpiredLinkDeleter
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// NewSweeper returns a Sweeper that runs every interval.
+func NewSweeper(repo ExpiredLinkDeleter, interval time.Duration, logger *zap.Logger) *Sweeper {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+	return &Sweeper{repo: repo, interval: interval, logger: logger}
+}
+
+// Run sweeps once immediately and then every interval until ctx is
+// cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.Sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep deletes the expired links once and logs how many were removed.
+func (s *Sweeper) Sweep(ctx context.Context) {
+	n, err := s.repo.DeleteExpiredShortLinks(ctx)
+	if err != nil {
+		s.logger.Error("expired link sweep failed", zap.Error(err))
+		return
+	}
+	s.logger.Info("swept expired links", zap.Int64("deleted", n))
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type fakeDeleter struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (f *fakeDeleter) DeleteExpiredShortLinks(context.Context) (int64, error) {
+	f.calls.Add(1)
+	return 4, f.err
+}
+
+func TestSweepLogsDeletedCount(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	NewSweeper(&fakeDeleter{}, time.Hour, zap.New(core)).Sweep(context.Background())
+
+	entries := logs.FilterMessage("swept expired links").All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d sweep log entries, want 1", len(entries))
+	}
+	if got := entries[0].ContextMap()["deleted"]; got != int64(4) {
+		t.Errorf("deleted = %v, want 4", got)
+	}
+}
+
+func TestSweepLogsError(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	NewSweeper(&fakeDeleter{err: errors.New("connection refused")}, time.Hour, zap.New(core)).Sweep(context.Background())
+
+	if logs.FilterMessage("expired link sweep failed").Len() != 1 {
+		t.Errorf("error not logged: %v", logs.All())
+	}
+}
+
+func TestRunSweepsUntilCancelled(t *testing.T) {
+	repo := &fakeDeleter{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewSweeper(repo, time.Millisecond, zap.NewNop()).Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for repo.calls.Load() < 3 {
+		select {
+		case <-deadline:
+			t.Fatal("sweeper did not run repeatedly")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestNewSweeperDefaultsInterval(t *testing.T) {
+	if s := NewSweeper(&fakeDeleter{}, 0, zap.NewNop()); s.interval != DefaultSweepInterval {
+		t.Errorf("interval = %v, want %v", s.interval, DefaultSweepInterval)
+	}
+}